@@ -0,0 +1,128 @@
+package elicit
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signedPattern   = `[-+]?\d+`
+	unsignedPattern = `\+?\d+`
+	floatPattern    = `[-+]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][-+]?\d+)?`
+	boolPattern     = `1|t|T|TRUE|true|True|0|f|F|FALSE|false|False`
+	durationPattern = `[-+]?(?:(?:\d+(?:\.\d*)?|\.\d+)(?:ns|us|µs|ms|s|m|h))+|0`
+	timePattern     = `\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[-+]\d{2}:\d{2}))?`
+	urlPattern      = `[a-zA-Z][a-zA-Z0-9+.-]*://[^\s,]+`
+	ipPattern       = `(?:\d{1,3}\.){3}\d{1,3}|[0-9a-fA-F]*:[0-9a-fA-F:.]*`
+)
+
+// registerBuiltin registers transforms for T and comma-separated slices of T,
+// which are only used if no transform supplied during setup matches.
+// The pattern must match a single value, and must not contain a comma.
+func registerBuiltin[T any](tm transformMap, pattern string, parse func(string) (T, error)) {
+	tm.add(`(?:`+pattern+`)`, func(params []string) (T, error) {
+		return parse(params[0])
+	}, true)
+
+	tm.add(`(?:(?:`+pattern+`),\s*)*(?:`+pattern+`)`, func(params []string) ([]T, error) {
+		ts := []T{}
+		for _, s := range strings.Split(params[0], ",") {
+			v, err := parse(strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			ts = append(ts, v)
+		}
+		return ts, nil
+	}, true)
+}
+
+func (tm transformMap) registerBuiltins() {
+	registerBuiltin(tm, boolPattern, strconv.ParseBool)
+
+	registerBuiltin(tm, signedPattern, func(s string) (int8, error) {
+		i, err := strconv.ParseInt(s, 10, 8)
+		return int8(i), err
+	})
+	registerBuiltin(tm, signedPattern, func(s string) (int16, error) {
+		i, err := strconv.ParseInt(s, 10, 16)
+		return int16(i), err
+	})
+	registerBuiltin(tm, signedPattern, func(s string) (int32, error) {
+		i, err := strconv.ParseInt(s, 10, 32)
+		return int32(i), err
+	})
+	registerBuiltin(tm, signedPattern, func(s string) (int64, error) {
+		return strconv.ParseInt(s, 10, 64)
+	})
+
+	registerBuiltin(tm, unsignedPattern, func(s string) (uint, error) {
+		i, err := strconv.ParseUint(s, 10, 0)
+		return uint(i), err
+	})
+	registerBuiltin(tm, unsignedPattern, func(s string) (uint8, error) {
+		i, err := strconv.ParseUint(s, 10, 8)
+		return uint8(i), err
+	})
+	registerBuiltin(tm, unsignedPattern, func(s string) (uint16, error) {
+		i, err := strconv.ParseUint(s, 10, 16)
+		return uint16(i), err
+	})
+	registerBuiltin(tm, unsignedPattern, func(s string) (uint32, error) {
+		i, err := strconv.ParseUint(s, 10, 32)
+		return uint32(i), err
+	})
+	registerBuiltin(tm, unsignedPattern, func(s string) (uint64, error) {
+		return strconv.ParseUint(s, 10, 64)
+	})
+
+	registerBuiltin(tm, floatPattern, func(s string) (float32, error) {
+		f, err := strconv.ParseFloat(s, 32)
+		return float32(f), err
+	})
+	registerBuiltin(tm, floatPattern, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+
+	registerBuiltin(tm, durationPattern, time.ParseDuration)
+
+	registerBuiltin(tm, timePattern, func(s string) (time.Time, error) {
+		if len(s) == len("2006-01-02") {
+			return time.Parse("2006-01-02", s)
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	})
+
+	registerBuiltin(tm, signedPattern, func(s string) (*big.Int, error) {
+		if i, ok := new(big.Int).SetString(s, 10); ok {
+			return i, nil
+		}
+		return nil, fmt.Errorf("invalid integer")
+	})
+	registerBuiltin(tm, floatPattern, func(s string) (*big.Float, error) {
+		if f, ok := new(big.Float).SetString(s); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("invalid number")
+	})
+
+	registerBuiltin(tm, urlPattern, func(s string) (url.URL, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+
+	registerBuiltin(tm, ipPattern, func(s string) (net.IP, error) {
+		if ip := net.ParseIP(s); ip != nil {
+			return ip, nil
+		}
+		return nil, fmt.Errorf("invalid IP address")
+	})
+}
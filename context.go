@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 )
 
 // Context stores test machinery and maintains state between specs/scenarios/steps
@@ -12,6 +14,7 @@ type Context struct {
 	specs          []*spec
 	stepImpls      stepImpls
 	transforms     transformMap
+	textDecoders   map[string]TextDecoder
 	beforeSpec     Hooks
 	afterSpec      Hooks
 	beforeScenario Hooks
@@ -19,6 +22,15 @@ type Context struct {
 	beforeStep     Hooks
 	afterStep      Hooks
 	unusedSteps    stepImpls
+	strictSteps    bool
+	failFast       bool
+	failedFast     bool
+	rerunFile      string
+	timingsFile    string
+	shuffleSeed    int64
+	shuffled       bool
+	settings       settings
+	runningStep    *step
 	log            log
 }
 
@@ -37,21 +49,83 @@ func (ctx *Context) WithSpecsFolder(path string) *Context {
 	return ctx
 }
 
-// WithSteps registers steps from the supplied map of patterns to functions
+// WithSteps registers steps from the supplied map of patterns to functions.
+// Since maps are unordered, the steps are registered in order of their patterns.
 func (ctx *Context) WithSteps(steps Steps) *Context {
-	for p, fn := range steps {
-		si := ctx.stepImpls.register(p, fn)
-		if si != nil {
-			ctx.unusedSteps = append(ctx.unusedSteps, si)
-		}
+	patterns := make([]string, 0, len(steps))
+	for p := range steps {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	for _, p := range patterns {
+		ctx.Step(p, steps[p])
+	}
+	return ctx
+}
+
+// Step registers a single step implementation against a pattern.
+func (ctx *Context) Step(pattern string, fn interface{}) *Context {
+	return ctx.StepWithPriority(pattern, 0, fn)
+}
+
+// StepWithPriority registers a single step implementation which is preferred
+// over any other matching implementation with a lower priority.
+func (ctx *Context) StepWithPriority(pattern string, priority int, fn interface{}) *Context {
+	if err := ctx.registerStep(pattern, fn, priority, nil); err != nil {
+		warn(err)
+	}
+	return ctx
+}
+
+func (ctx *Context) registerStep(pattern string, fn interface{}, priority int, lib *StepLibrary) error {
+	si, err := ctx.stepImpls.register(pattern, ctx.bindStepContext(fn), priority)
+	if err != nil {
+		return err
 	}
+
+	si.declared = fn
+
+	si.library = lib
+	ctx.checkConflicts(si)
+	ctx.unusedSteps = append(ctx.unusedSteps, si)
+	return nil
+}
+
+// WithStrictStepMatching disables the resolution of steps matching more than
+// one implementation, treating them all as ambiguous.
+func (ctx *Context) WithStrictStepMatching() *Context {
+	ctx.strictSteps = true
+	return ctx
+}
+
+// FailFast skips the remaining specs and scenarios once a scenario fails or panics.
+// Any hooks for the specs and scenarios already running are still run.
+func (ctx *Context) FailFast() *Context {
+	ctx.failFast = true
+	return ctx
+}
+
+// WithScenarioTimeout sets the default time allowed for each scenario to run.
+// Specs and scenarios may override it with a "timeout" setting.
+func (ctx *Context) WithScenarioTimeout(timeout time.Duration) *Context {
+	ctx.settings.scenarioTimeout = timeout
+	return ctx
+}
+
+// WithStepTimeout sets the default time allowed for each step to run.
+// Specs and scenarios may override it with a "step-timeout" setting.
+func (ctx *Context) WithStepTimeout(timeout time.Duration) *Context {
+	ctx.settings.stepTimeout = timeout
 	return ctx
 }
 
 // WithTransforms registers step argument transforms from the suppled map of patterns to functions
 func (ctx *Context) WithTransforms(txs Transforms) *Context {
 	for p, fn := range txs {
-		ctx.transforms.register(p, fn)
+		if err := ctx.transforms.register(p, fn); err != nil {
+			warn(err)
+		}
 	}
 	return ctx
 }
@@ -98,8 +172,35 @@ func (ctx *Context) RunTests(ctxT *testing.T) *Context {
 
 	ctx.validate()
 
+	if *rerun != "" {
+		if ids, err := readRerunFile(*rerun); err != nil {
+			warn(fmt.Errorf("reading rerun file: %s", err))
+		} else {
+			ctx.selectScenarios(ids)
+		}
+	}
+
+	if *shardFlag != "" {
+		ctx.selectShardFromFlags()
+	}
+
+	ctx.shuffleFromFlags()
+
+	if *dryRun {
+		ctx.dryRun(ctxT)
+		ctx.warnUnusedSteps()
+		ctx.log.writeToConsole()
+		ctx.log.writeToFile()
+		return ctx
+	}
+
 	for _, spec := range ctx.specs {
 
+		if ctx.failedFast {
+			spec.skip(ctxT, failFastReason)
+			continue
+		}
+
 		var hookErr error
 		if hookErr = ctx.beforeSpec.run("before spec"); hookErr != nil {
 			spec.skipAllScenarios()
@@ -123,11 +224,27 @@ func (ctx *Context) RunTests(ctxT *testing.T) *Context {
 
 			}
 		})
+
+		ctx.checkFailFast(spec.result)
 	}
 
+	ctx.warnUnusedSteps()
+
 	ctx.log.writeToConsole()
 	ctx.log.writeToFile()
 
+	if ctx.rerunFile != "" {
+		if err := ctx.writeRerunFile(ctx.rerunFile); err != nil {
+			warn(fmt.Errorf("writing rerun file: %s", err))
+		}
+	}
+
+	if ctx.timingsFile != "" {
+		if err := ctx.writeTimingsFile(ctx.timingsFile); err != nil {
+			warn(fmt.Errorf("writing timings file: %s", err))
+		}
+	}
+
 	if allSkipped {
 		ctxT.SkipNow()
 	}
@@ -135,6 +252,35 @@ func (ctx *Context) RunTests(ctxT *testing.T) *Context {
 	return ctx
 }
 
+// filterScenarios removes the scenarios which shouldn't run, along with specs left without any
+func (ctx *Context) filterScenarios(keep func(*scenario) bool) {
+	specs := []*spec{}
+
+	for _, spec := range ctx.specs {
+		scenarios := []*scenario{}
+		for _, scenario := range spec.scenarios {
+			if keep(scenario) {
+				scenarios = append(scenarios, scenario)
+			}
+		}
+
+		if len(scenarios) > 0 {
+			spec.scenarios = scenarios
+			specs = append(specs, spec)
+		}
+	}
+
+	ctx.specs = specs
+}
+
+// checkFailFast stops any more scenarios from running after a failure, if failing fast
+func (ctx *Context) checkFailFast(r result) {
+	switch r {
+	case failed, timedOut, panicked:
+		ctx.failedFast = ctx.failedFast || ctx.failFast
+	}
+}
+
 func (ctx *Context) validate() {
 	if len(ctx.specs) == 0 {
 		fmt.Fprintln(os.Stderr, "warning: No specifications found. Add a folder containing *.md files with Context.WithSpecsFolder().")
@@ -153,12 +299,25 @@ func (ctx *Context) checkTransforms() {
 		fn := reflect.ValueOf(impl.fn)
 		fnSig := fn.Type()
 
-		paramCount, _, _ := ctx.stepImpls.countStepImplParams(fn)
+		paramCount, _ := ctx.stepImpls.countStepImplParams(fn, impl.regex.NumSubexp())
 
-		for p := 1; p < paramCount; p++ {
-			pType := fnSig.In(p)
+		pTypes := []reflect.Type{}
+		if ctx.transforms.bindsFields(impl) {
+			if impl.missing != "" {
+				fmt.Fprintf(os.Stderr, stepWarnNoField, impl, impl.missing, fnSig.In(1))
+				continue
+			}
+			for _, f := range impl.fields {
+				pTypes = append(pTypes, fnSig.In(1).Field(f).Type)
+			}
+		} else {
+			for p := 1; p < paramCount; p++ {
+				pTypes = append(pTypes, variadicParamType(fnSig, p))
+			}
+		}
 
-			if len(ctx.transforms[pType]) == 0 {
+		for _, pType := range pTypes {
+			if !ctx.transforms.canConvert(pType) {
 				fmt.Fprintf(os.Stderr, stepWarnNoTransform, impl, pType)
 			}
 		}
@@ -173,7 +332,10 @@ func (ctx *Context) resolveSteps() {
 			}
 		}
 	}
+}
 
+// warnUnusedSteps reports the steps which nothing matched, once any steps run by other steps have been matched too.
+func (ctx *Context) warnUnusedSteps() {
 	for _, impl := range ctx.unusedSteps {
 		fmt.Fprintf(os.Stderr, stepWarnNotUsed, impl)
 	}
@@ -182,6 +344,7 @@ func (ctx *Context) resolveSteps() {
 type stepImplCandidate struct {
 	impl *stepImpl
 	call func(t *testing.T)
+	err  error
 }
 
 func (ctx *Context) matchStepImpl(s *step) {
@@ -191,17 +354,24 @@ func (ctx *Context) matchStepImpl(s *step) {
 	}
 
 	candidates := ctx.getStepImplCandidates(s)
+	checkOverlaps(s, candidates)
+
+	if !ctx.strictSteps {
+		candidates = mostSpecific(candidates)
+	}
 
 	if len(candidates) == 1 {
 		c := candidates[0]
 		ctx.recordStepImplAsUsed(c)
-		s.setImpl(c.call)
+		s.setImpl(c.impl, c.call)
+		s.err = c.err
 	} else if len(candidates) > 1 {
 		warning := fmt.Sprintf(stepWarnAmbiguous, s.text)
 		for _, c := range candidates {
 			warning += fmt.Sprintf("            - %s\n", c.impl)
 		}
 		fmt.Fprint(os.Stderr, warning)
+		s.err = fmt.Errorf("step matches %d implementations", len(candidates))
 	}
 }
 
@@ -210,16 +380,48 @@ func (ctx *Context) getStepImplCandidates(s *step) []stepImplCandidate {
 
 	for _, impl := range ctx.stepImpls {
 		fn := reflect.ValueOf(impl.fn)
-		params := impl.regex.FindStringSubmatch(s.text)
+		params, matched := impl.match(s.text)
 
-		if convertedParams, ok := ctx.transforms.convertParams(s, fn, params); ok {
+		convertedParams, ok, err := ctx.transforms.convertParams(s, impl, params, matched)
+		if err != nil {
+			candidates = append(candidates, stepImplCandidate{impl, s.createFailedCall(err), err})
+		} else if ok {
 			call := s.createCall(fn, convertedParams)
-			candidates = append(candidates, stepImplCandidate{impl, call})
+			candidates = append(candidates, stepImplCandidate{impl, call, nil})
 		}
 	}
 	return candidates
 }
 
+// mostSpecific filters candidates down to those with the most specific patterns,
+// preserving their registration order.
+func mostSpecific(candidates []stepImplCandidate) []stepImplCandidate {
+	if len(candidates) < 2 {
+		return candidates
+	}
+
+	best := []stepImplCandidate{}
+	var bestSpec specificity
+
+	for _, c := range candidates {
+		spec := c.impl.specificity()
+		cmp := 1
+		if len(best) > 0 {
+			cmp = spec.compare(bestSpec)
+		}
+
+		switch {
+		case cmp > 0:
+			best = []stepImplCandidate{c}
+			bestSpec = spec
+		case cmp == 0:
+			best = append(best, c)
+		}
+	}
+
+	return best
+}
+
 func (ctx *Context) recordStepImplAsUsed(c stepImplCandidate) {
 	for i, us := range ctx.unusedSteps {
 		if us == c.impl {
@@ -230,6 +432,10 @@ func (ctx *Context) recordStepImplAsUsed(c stepImplCandidate) {
 	}
 }
 
+func warn(err error) {
+	fmt.Fprintf(os.Stderr, "warning: %s.\n", err)
+}
+
 func (hs Hooks) run(stage string) error {
 	var hookErr error
 	for _, h := range hs {
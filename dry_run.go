@@ -0,0 +1,55 @@
+package elicit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// dryRun checks that every step of every scenario could run, without running any steps or hooks.
+// Steps which could run are reported as skipped, and those which couldn't fail their scenario.
+func (ctx *Context) dryRun(ctxT *testing.T) {
+	for _, spec := range ctx.specs {
+		ctxT.Run(spec.path+"/"+spec.name, func(specT *testing.T) {
+			for _, scenario := range spec.scenarios {
+				specT.Run(scenario.name, scenario.check)
+
+				if scenario.result > spec.result {
+					spec.result = scenario.result
+				}
+			}
+		})
+	}
+}
+
+// check reports any steps in the scenario which couldn't run
+func (s *scenario) check(scenarioT *testing.T) {
+	if len(s.steps) == 0 {
+		s.result = pending
+		scenarioT.SkipNow()
+	}
+
+	for _, step := range s.steps {
+		if err := step.check(); err != nil {
+			step.result = failed
+			fmt.Fprintf(&step.log, "%s: %s\n", step.location(), err)
+			s.result = failed
+			scenarioT.Fail()
+		} else {
+			step.result = skipped
+		}
+	}
+}
+
+// check explains why the step couldn't run, if it couldn't
+func (s *step) check() error {
+	switch {
+	case len(s.params) > 0:
+		return fmt.Errorf("no table has columns for %s", strings.Join(s.params, ", "))
+	case s.err != nil:
+		return s.err
+	case s.impl == nil:
+		return fmt.Errorf("no step implementation matches")
+	}
+	return nil
+}
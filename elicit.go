@@ -8,7 +8,16 @@ import (
 )
 
 var (
-	reportFile = flag.String("elicit.report", "", "Path to save an execution report")
+	reportFile  = flag.String("elicit.report", "", "Path to save an execution report")
+	retries     = flag.Int("elicit.retries", 0, "Number of times to retry a failed scenario")
+	dryRun      = flag.Bool("elicit.dryrun", false, "Check every step can run, without running any steps or hooks")
+	failFast    = flag.Bool("elicit.failfast", false, "Skip the remaining scenarios once one has failed")
+	rerunFile   = flag.String("elicit.rerunfile", "", "Path to save a list of the scenarios which failed")
+	rerun       = flag.String("elicit.rerun", "", "Path to a list of scenarios to run, saved by -elicit.rerunfile")
+	shardFlag   = flag.String("elicit.shard", "", "Run only the i'th of n parts of the scenarios, given as i/n")
+	timingsFile = flag.String("elicit.timingsfile", "", "Path to save how long each scenario took")
+	timingsFlag = flag.String("elicit.timings", "", "Path to timings saved by -elicit.timingsfile, used to balance shards")
+	shuffle     = flag.String("elicit.shuffle", "off", "Randomise the order of specs and scenarios: on, off or a seed to reproduce an order")
 )
 
 // Steps are used to register step implemenations against regex patterns
@@ -38,6 +47,8 @@ func New() *Context {
 	}
 
 	ctx.log.ctx = ctx
+	ctx.settings.retries = *retries
+	ctx.failFast = *failFast
 
 	if *reportFile != "" {
 		if reportFileAbs, err := filepath.Abs(*reportFile); err != nil {
@@ -47,7 +58,24 @@ func New() *Context {
 		}
 	}
 
+	if *rerunFile != "" {
+		if rerunFileAbs, err := filepath.Abs(*rerunFile); err != nil {
+			panic(fmt.Errorf("determining absolute path for %s: %s", *rerunFile, err))
+		} else {
+			ctx.rerunFile = rerunFileAbs
+		}
+	}
+
+	if *timingsFile != "" {
+		if timingsFileAbs, err := filepath.Abs(*timingsFile); err != nil {
+			panic(fmt.Errorf("determining absolute path for %s: %s", *timingsFile, err))
+		} else {
+			ctx.timingsFile = timingsFileAbs
+		}
+	}
+
 	ctx.transforms.init()
+	ctx.registerTextDecoders()
 
 	return ctx
 }
@@ -167,9 +167,11 @@ func quoteOutput(s string) string {
 const modfile = `
 module github.com/mpwalkerdine/elicit/testmod
 
-require github.com/mpwalkerdine/elicit latest
+go 1.18
 
-replace github.com/mpwalkerdine/elicit latest => %s
+require github.com/mpwalkerdine/elicit v0.0.0
+
+replace github.com/mpwalkerdine/elicit => %s
 `
 
 const testfile = `
@@ -1,3 +1,5 @@
 module github.com/mpwalkerdine/elicit
 
+go 1.18
+
 require github.com/russross/blackfriday v1.5.1
@@ -55,9 +55,15 @@ func (l *log) fillBuffer(forceVerbose bool) {
 
 	verbose := l.isVerbose(forceVerbose)
 
+	if l.ctx.shuffled {
+		fmt.Fprintf(&l.buffer, "\n\nShuffled with -elicit.shuffle=%d\n", l.ctx.shuffleSeed)
+	}
+
 	for _, spec := range l.ctx.specs {
 		l.logSpec(spec, verbose)
 	}
+
+	l.writeFlakySummary()
 }
 
 func (l *log) logSpec(spec *spec, verbose bool) {
@@ -79,8 +85,21 @@ func (l *log) logScenario(scenario *scenario, verbose bool) {
 
 	l.writeScenarioHeader(scenario)
 
+	for i, attempt := range scenario.attempts {
+		l.writeAttemptHeader(i+1, attempt.result)
+
+		for _, step := range attempt.steps {
+			l.writeStepResult(step, "    ")
+		}
+		l.writeLn()
+	}
+
+	if len(scenario.attempts) > 0 {
+		l.writeAttemptHeader(len(scenario.attempts)+1, scenario.result)
+	}
+
 	for _, step := range scenario.steps {
-		l.writeStepResult(step)
+		l.writeStepResult(step, "    ")
 	}
 }
 
@@ -100,7 +119,7 @@ func (l *log) writeSpecHeader(s *spec) {
 	case skipped:
 		name = l.blue(name)
 		underline = l.blue(underline)
-	case failed, panicked:
+	case failed, timedOut, panicked:
 		name = l.red(name)
 		underline = l.red(underline)
 	}
@@ -131,21 +150,69 @@ func (l *log) writeScenarioHeader(s *scenario) {
 	case skipped:
 		name = l.blue(name)
 		underline = l.blue(underline)
-	case failed, panicked:
+	case failed, timedOut, panicked:
 		name = l.red(name)
 		underline = l.red(underline)
 	}
 
-	fmt.Fprintf(&l.buffer, "\n%s\n%s\n%s\n\n", name, underline, s.result)
+	result := s.result.String()
+	if s.isFlaky() {
+		result += " (flaky)"
+	} else if s.skipReason != "" {
+		result += " (" + s.skipReason + ")"
+	}
+
+	fmt.Fprintf(&l.buffer, "\n%s\n%s\n%s\n\n", name, underline, result)
+}
+
+func (l *log) writeAttemptHeader(n int, r result) {
+	header := fmt.Sprintf("Attempt %d: %s", n, r)
+
+	switch r {
+	case pending:
+		header = l.yellow(header)
+	case skipped:
+		header = l.blue(header)
+	case failed, timedOut, panicked:
+		header = l.red(header)
+	}
+
+	fmt.Fprintf(&l.buffer, "%s\n\n", header)
+}
+
+// writeFlakySummary lists the scenarios which only passed when retried
+func (l *log) writeFlakySummary() {
+	flaky := []string{}
+	for _, spec := range l.ctx.specs {
+		for _, scenario := range spec.scenarios {
+			if scenario.isFlaky() {
+				flaky = append(flaky, fmt.Sprintf("%s/%s/%s passed on attempt %d",
+					spec.path, spec.name, scenario.name, len(scenario.attempts)+1))
+			}
+		}
+	}
+
+	if len(flaky) == 0 {
+		return
+	}
+
+	title := "Flaky Scenarios"
+	fmt.Fprintf(&l.buffer, "\n\n%s\n%s\n%s\n",
+		l.yellow(title), l.yellow(strings.Repeat("=", len(title))), strings.Join(flaky, "\n"))
 }
 
-func (l *log) writeStepResult(s *step) {
+func (l *log) writeStepResult(s *step, indent string) {
 	text := l.getStepText(s)
 
-	fmt.Fprintf(&l.buffer, "    %s\n", text)
+	fmt.Fprintf(&l.buffer, "%s%s\n", indent, text)
+
+	// Steps run by this step are nested beneath it
+	for _, child := range s.children {
+		l.writeStepResult(child, indent+"    ")
+	}
 
 	if s.log.Len() > 0 {
-		leftPad := "        "
+		leftPad := indent + "    "
 		stepLog := s.log.String()
 		stepLog = strings.TrimSuffix(stepLog, "\n")
 		lines := strings.Split(stepLog, "\n")
@@ -168,6 +235,9 @@ func (l *log) getStepText(s *step) string {
 	case failed:
 		prefix = l.red("✘")
 		text = l.red(text)
+	case timedOut:
+		prefix = l.red("⌛")
+		text = l.red(text)
 	case panicked:
 		prefix = l.red("⚡")
 		text = l.red(text)
@@ -191,6 +261,10 @@ func (l *log) getStepSuffix(s *step) string {
 	if tables > 0 {
 		suffix += strings.Repeat(" ☷", tables)
 	}
+
+	if s.definition != nil && s.definition.library != nil {
+		suffix += fmt.Sprintf(" (%s)", s.definition.library)
+	}
 	return suffix
 }
 
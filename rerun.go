@@ -0,0 +1,89 @@
+package elicit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A rerun file lists the scenarios which failed, one per line, as the scenario's ID
+// followed by a tab and its location in the spec, e.g.
+//
+//	specs/orders.md/Orders/Cancelling an Order	specs/orders.md:42
+//
+// Only the ID is used to select the scenarios to rerun, so it remains valid as the spec is edited.
+
+// id identifies the scenario by its spec's path and name and its own name
+func (s *scenario) id() string {
+	return s.spec.path + "/" + s.spec.name + "/" + s.name
+}
+
+// location identifies where the scenario is written in its spec file
+func (s *scenario) location() string {
+	if s.line == 0 {
+		return s.spec.path
+	}
+	return fmt.Sprintf("%s:%d", s.spec.path, s.line)
+}
+
+// writeRerunFile saves the list of scenarios which failed
+func (ctx *Context) writeRerunFile(path string) error {
+	var buffer bytes.Buffer
+	for _, spec := range ctx.specs {
+		for _, scenario := range spec.scenarios {
+			switch scenario.result {
+			case failed, timedOut, panicked:
+				fmt.Fprintf(&buffer, "%s\t%s\n", scenario.id(), scenario.location())
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bytes.TrimSpace(buffer.Bytes()), 0644)
+}
+
+// readRerunFile reads the IDs of the scenarios listed in a rerun file
+func readRerunFile(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.SplitN(scanner.Text(), "\t", 2)[0]; strings.TrimSpace(id) != "" {
+			ids[id] = true
+		}
+	}
+	return ids, scanner.Err()
+}
+
+// selectScenarios removes any scenarios which aren't listed
+func (ctx *Context) selectScenarios(ids map[string]bool) {
+	found := map[string]bool{}
+	ctx.filterScenarios(func(s *scenario) bool {
+		found[s.id()] = found[s.id()] || ids[s.id()]
+		return ids[s.id()]
+	})
+
+	missing := []string{}
+	for id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, id := range missing {
+		warn(fmt.Errorf("scenario %q in the rerun file was not found", id))
+	}
+}
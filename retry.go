@@ -0,0 +1,10 @@
+package elicit
+
+import (
+	"fmt"
+)
+
+// attemptName names the subtest which runs the nth attempt at a scenario which may be retried
+func attemptName(n int) string {
+	return fmt.Sprintf("attempt %d", n)
+}
@@ -1,14 +1,32 @@
 package elicit
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 type scenario struct {
-	context *Context
-	spec    *spec
-	name    string
-	steps   []*step
-	tables  []stringTable
-	result  result
+	context    *Context
+	spec       *spec
+	name       string
+	line       int
+	steps      []*step
+	tables     []stringTable
+	settings   settings
+	deadline   time.Time
+	attempts   []attempt
+	duration   time.Duration
+	result     result
+	skipReason string
+}
+
+// failFastReason explains why scenarios are skipped after a failure when failing fast
+const failFastReason = "not run due to fail-fast"
+
+// attempt records a failed run of a scenario which was then retried
+type attempt struct {
+	steps  []*step
+	result result
 }
 
 func (s *scenario) run(scenarioT *testing.T) {
@@ -16,6 +34,10 @@ func (s *scenario) run(scenarioT *testing.T) {
 		s.result = pending
 	}
 
+	if timeout := s.effectiveSettings().scenarioTimeout; timeout > 0 {
+		s.deadline = time.Now().Add(timeout)
+	}
+
 	for _, step := range s.steps {
 
 		if s.result != passed {
@@ -48,3 +70,36 @@ func (s *scenario) runStep(scenarioT *testing.T, step *step) {
 
 	step.run(scenarioT)
 }
+
+// skip reports the scenario as skipped without running it
+func (s *scenario) skip(specT *testing.T, reason string) {
+	s.result = skipped
+	s.skipReason = reason
+
+	specT.Run(s.name, func(scenarioT *testing.T) {
+		scenarioT.Skip(reason)
+	})
+}
+
+// retry records the steps as they ran in the failed attempt,
+// then resets them and the scenario so they can be run again.
+func (s *scenario) retry(initial []step) {
+	a := attempt{result: s.result}
+	for i, step := range s.steps {
+		ran := *step
+		a.steps = append(a.steps, &ran)
+		*step = initial[i]
+	}
+	s.attempts = append(s.attempts, a)
+	s.result = passed
+}
+
+// isFlaky reports whether the scenario passed after failing
+func (s *scenario) isFlaky() bool {
+	return s.result == passed && len(s.attempts) > 0
+}
+
+// effectiveSettings are the scenario's settings, falling back to those of its spec and context
+func (s *scenario) effectiveSettings() settings {
+	return s.settings.inherit(s.spec.settings).inherit(s.context.settings)
+}
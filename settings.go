@@ -0,0 +1,87 @@
+package elicit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settings control how specs and scenarios run. They are set for the whole context,
+// and may be overridden in markdown by an HTML comment following a spec or scenario heading:
+//
+//	<!-- elicit: timeout=10s step-timeout=2s retries=2 -->
+type settings struct {
+	scenarioTimeout time.Duration
+	stepTimeout     time.Duration
+	retries         int
+	hasRetries      bool
+}
+
+var settingsComment = regexp.MustCompile(`(?s)^\s*<!--\s*elicit:(.*)-->\s*$`)
+
+// parse reads any settings from an HTML comment
+func (s *settings) parse(html string) error {
+	m := settingsComment.FindStringSubmatch(html)
+	if m == nil {
+		return nil
+	}
+
+	for _, field := range strings.Fields(m[1]) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("setting %q must be of the form name=value", field)
+		}
+
+		if err := s.set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("setting %q: %s", field, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *settings) set(name, value string) error {
+	switch name {
+	case "timeout":
+		return parseTimeout(value, &s.scenarioTimeout)
+	case "step-timeout":
+		return parseTimeout(value, &s.stepTimeout)
+	case "retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("retries must be a whole number")
+		}
+		s.retries, s.hasRetries = n, true
+		return nil
+	default:
+		return fmt.Errorf("unknown setting")
+	}
+}
+
+func parseTimeout(value string, timeout *time.Duration) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	*timeout = d
+	return nil
+}
+
+// inherit fills in any settings not already set from the parent's settings
+func (s settings) inherit(parent settings) settings {
+	if s.scenarioTimeout == 0 {
+		s.scenarioTimeout = parent.scenarioTimeout
+	}
+	if s.stepTimeout == 0 {
+		s.stepTimeout = parent.stepTimeout
+	}
+	if !s.hasRetries {
+		s.retries, s.hasRetries = parent.retries, parent.hasRetries
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package elicit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shard identifies which of a number of equal parts of the scenarios to run, counting from 1
+type shard struct {
+	index, count int
+}
+
+// parseShard reads a shard of the form i/n
+func parseShard(value string) (shard, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) == 2 {
+		index, indexErr := strconv.Atoi(parts[0])
+		count, countErr := strconv.Atoi(parts[1])
+		if indexErr == nil && countErr == nil && index >= 1 && index <= count {
+			return shard{index, count}, nil
+		}
+	}
+	return shard{}, fmt.Errorf("shard %q must be of the form i/n, where i is between 1 and n", value)
+}
+
+// selectShardFromFlags runs only the shard given by -elicit.shard, balanced by any -elicit.timings
+func (ctx *Context) selectShardFromFlags() {
+	sh, err := parseShard(*shardFlag)
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	timings := map[string]time.Duration{}
+	if *timingsFlag != "" {
+		if timings, err = readTimingsFile(*timingsFlag); err != nil {
+			warn(fmt.Errorf("reading timings file: %s", err))
+		}
+	}
+
+	ctx.selectShard(sh, timings)
+}
+
+// selectShard removes any scenarios which belong to other shards. Scenarios are assigned to shards
+// by a hash of their spec's path and their name, unless timings are supplied, in which case the
+// longest scenarios are assigned first, each to the shard with the least to run so far.
+// Scenarios without a timing are assumed to take the average time.
+func (ctx *Context) selectShard(sh shard, timings map[string]time.Duration) {
+	assigned := map[*scenario]int{}
+
+	if len(timings) == 0 {
+		for _, spec := range ctx.specs {
+			for _, scenario := range spec.scenarios {
+				assigned[scenario] = int(scenario.hash() % uint32(sh.count))
+			}
+		}
+	} else {
+		assigned = ctx.balanceShards(sh.count, timings)
+	}
+
+	ctx.filterScenarios(func(s *scenario) bool {
+		return assigned[s] == sh.index-1
+	})
+}
+
+func (ctx *Context) balanceShards(count int, timings map[string]time.Duration) map[*scenario]int {
+	var total time.Duration
+	for _, d := range timings {
+		total += d
+	}
+	average := total / time.Duration(len(timings))
+
+	type timedScenario struct {
+		scenario *scenario
+		duration time.Duration
+	}
+
+	scenarios := []timedScenario{}
+	for _, spec := range ctx.specs {
+		for _, scenario := range spec.scenarios {
+			d, ok := timings[scenario.id()]
+			if !ok {
+				d = average
+			}
+			scenarios = append(scenarios, timedScenario{scenario, d})
+		}
+	}
+
+	sort.SliceStable(scenarios, func(i, j int) bool {
+		if scenarios[i].duration != scenarios[j].duration {
+			return scenarios[i].duration > scenarios[j].duration
+		}
+		return scenarios[i].scenario.id() < scenarios[j].scenario.id()
+	})
+
+	assigned := map[*scenario]int{}
+	loads := make([]time.Duration, count)
+	for _, ts := range scenarios {
+		least := 0
+		for i, load := range loads {
+			if load < loads[least] {
+				least = i
+			}
+		}
+		assigned[ts.scenario] = least
+		loads[least] += ts.duration
+	}
+	return assigned
+}
+
+// hash is stable between runs, so the scenario stays in the same shard
+func (s *scenario) hash() uint32 {
+	sum := sha256.Sum256([]byte(s.spec.path + "/" + s.name))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// A timings file records how long each scenario took to run, one per line, as the scenario's ID
+// followed by a tab and its duration. The timings files of each shard may be concatenated.
+
+// writeTimingsFile saves how long each scenario which ran took
+func (ctx *Context) writeTimingsFile(path string) error {
+	var buffer bytes.Buffer
+	for _, spec := range ctx.specs {
+		for _, scenario := range spec.scenarios {
+			if scenario.duration > 0 {
+				fmt.Fprintf(&buffer, "%s\t%s\n", scenario.id(), scenario.duration)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bytes.TrimSpace(buffer.Bytes()), 0644)
+}
+
+// readTimingsFile reads how long scenarios took from a timings file
+func readTimingsFile(path string) (map[string]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	timings := map[string]time.Duration{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d must be a scenario and a duration separated by a tab", line)
+		}
+
+		d, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		timings[fields[0]] = d
+	}
+	return timings, scanner.Err()
+}
@@ -0,0 +1,44 @@
+package elicit
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// shuffleFromFlags randomises the order of specs and scenarios as requested by -elicit.shuffle
+func (ctx *Context) shuffleFromFlags() {
+	switch *shuffle {
+	case "off":
+		return
+	case "on":
+		ctx.shuffle(time.Now().UnixNano())
+	default:
+		seed, err := strconv.ParseInt(*shuffle, 10, 64)
+		if err != nil {
+			warn(fmt.Errorf("shuffle %q must be on, off or a seed", *shuffle))
+			return
+		}
+		ctx.shuffle(seed)
+	}
+}
+
+// shuffle randomises the order of specs, and of the scenarios within each spec.
+// The same seed always gives the same order for the same specs.
+func (ctx *Context) shuffle(seed int64) {
+	r := rand.New(rand.NewSource(seed))
+
+	r.Shuffle(len(ctx.specs), func(i, j int) {
+		ctx.specs[i], ctx.specs[j] = ctx.specs[j], ctx.specs[i]
+	})
+
+	for _, spec := range ctx.specs {
+		r.Shuffle(len(spec.scenarios), func(i, j int) {
+			spec.scenarios[i], spec.scenarios[j] = spec.scenarios[j], spec.scenarios[i]
+		})
+	}
+
+	ctx.shuffleSeed = seed
+	ctx.shuffled = true
+}
@@ -3,6 +3,7 @@ package elicit
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 type spec struct {
@@ -11,6 +12,7 @@ type spec struct {
 	name      string
 	scenarios []*scenario
 	tables    []stringTable
+	settings  settings
 	result    result
 }
 
@@ -22,6 +24,7 @@ const (
 	skipped
 	pending
 	failed
+	timedOut
 	panicked
 	numResultTypes
 )
@@ -38,6 +41,8 @@ func (r result) String() string {
 		return "Skipped"
 	case failed:
 		return "Failed"
+	case timedOut:
+		return "Timed Out"
 	case panicked:
 		return "Panicked"
 	case passed:
@@ -50,18 +55,20 @@ func (r result) String() string {
 func (s *spec) run(specT *testing.T) {
 	for _, scenario := range s.scenarios {
 
-		var hookErr error
-		if hookErr = s.context.beforeScenario.run("before scenario"); hookErr != nil {
-			scenario.result = panicked
-		}
+		if s.context.failedFast {
+			scenario.skip(specT, failFastReason)
+		} else {
+			start := time.Now()
+			hookErr := s.context.beforeScenario.run("before scenario")
 
-		specT.Run(scenario.name, func(scenarioT *testing.T) {
-			if hookErr != nil {
-				scenarioT.FailNow()
-			}
+			specT.Run(scenario.name, func(scenarioT *testing.T) {
+				s.runScenario(scenarioT, scenario, hookErr)
+			})
 
-			s.runScenario(scenarioT, scenario)
-		})
+			scenario.duration = time.Since(start)
+
+			s.context.checkFailFast(scenario.result)
+		}
 
 		if scenario.result > s.result {
 			s.result = scenario.result
@@ -69,14 +76,55 @@ func (s *spec) run(specT *testing.T) {
 	}
 
 	switch s.result {
-	case panicked, failed:
+	case panicked, timedOut, failed:
 		specT.Fail()
 	case skipped, pending:
 		specT.SkipNow()
 	}
 }
 
-func (s *spec) runScenario(scenarioT *testing.T, scenario *scenario) {
+// runScenario runs the scenario, retrying it from the start while it fails and retries remain.
+// Scenarios which may be retried run each attempt as a subtest, so the attempts are reported separately.
+func (s *spec) runScenario(scenarioT *testing.T, scenario *scenario, hookErr error) {
+	retries := scenario.effectiveSettings().retries
+	if retries == 0 {
+		s.runAttempt(scenarioT, scenario, hookErr)
+		return
+	}
+
+	initial := make([]step, len(scenario.steps))
+	for i, step := range scenario.steps {
+		initial[i] = *step
+	}
+
+	for {
+		scenarioT.Run(attemptName(len(scenario.attempts)+1), func(attemptT *testing.T) {
+			s.runAttempt(attemptT, scenario, hookErr)
+		})
+
+		switch scenario.result {
+		case passed:
+			return
+		case skipped, pending:
+			scenarioT.SkipNow()
+		}
+
+		if len(scenario.attempts) == retries {
+			return
+		}
+
+		scenario.retry(initial)
+		hookErr = s.context.beforeScenario.run("before scenario")
+	}
+}
+
+// runAttempt runs the scenario once its before scenario hooks have run
+func (s *spec) runAttempt(scenarioT *testing.T, scenario *scenario, hookErr error) {
+	if hookErr != nil {
+		scenario.result = panicked
+		scenarioT.FailNow()
+	}
+
 	// Ensure the after scenario hooks are run regardless of the result
 	defer func() {
 		if hookErr := s.context.afterScenario.run("after scenario"); hookErr != nil {
@@ -88,6 +136,19 @@ func (s *spec) runScenario(scenarioT *testing.T, scenario *scenario) {
 	scenario.run(scenarioT)
 }
 
+// skip reports the spec and all of its scenarios as skipped without running them
+func (s *spec) skip(ctxT *testing.T, reason string) {
+	for _, scenario := range s.scenarios {
+		scenario.result = skipped
+		scenario.skipReason = reason
+	}
+	s.result = skipped
+
+	ctxT.Run(s.path+"/"+s.name, func(specT *testing.T) {
+		specT.Skip(reason)
+	})
+}
+
 func (s *spec) skipAllScenarios() {
 	for _, scenario := range s.scenarios {
 		scenario.result = skipped
@@ -27,6 +27,10 @@ type specParser struct {
 	tableHeaders    []string
 	tableRow        []string
 	tableRows       stringTable
+	tableAlignments []Alignment
+	tableCellCounts [][]int
+	stepLines       []int
+	scenarioLines   []int
 }
 
 func (p *specParser) parseSpecFolder(directory string) {
@@ -46,6 +50,10 @@ func (p *specParser) loadFromFile() {
 		panic(fmt.Errorf("parsing spec file: %s: %s", p.currentPath, err))
 	}
 
+	p.stepLines = findStepLines(specText)
+	p.scenarioLines = findScenarioLines(specText)
+	p.tableCellCounts = findTableCellCounts(specText)
+
 	// Strip out non-step items so they're not parsed
 	specText = regexp.MustCompile(`(?m)^([-*]|\d\.) `).ReplaceAllLiteral(specText, []byte{})
 
@@ -55,6 +63,108 @@ func (p *specParser) loadFromFile() {
 	p.closeSpec()
 }
 
+// findStepLines lists the line numbers of step list items, ignoring fenced code blocks.
+func findStepLines(specText []byte) []int {
+	lines := []int{}
+	scanLines(specText, func(n int, line, previous string) {
+		if strings.HasPrefix(line, "+ ") {
+			lines = append(lines, n)
+		}
+	})
+	return lines
+}
+
+// findScenarioLines lists the line numbers of scenario headings, ignoring fenced code blocks.
+// Underlined headings are numbered by the line of their text.
+func findScenarioLines(specText []byte) []int {
+	lines := []int{}
+	scanLines(specText, func(n int, line, previous string) {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			lines = append(lines, n)
+		case strings.TrimSpace(previous) != "" && strings.Trim(strings.TrimSpace(line), "-") == "" &&
+			strings.TrimSpace(line) != "" && !strings.HasPrefix(previous, "+ "):
+			lines = append(lines, n-1)
+		}
+	})
+	return lines
+}
+
+// scanLines calls fn with the number and text of each line outside fenced code blocks,
+// along with the text of the preceding line.
+func scanLines(specText []byte, fn func(n int, line, previous string)) {
+	fence := ""
+	previous := ""
+
+	for i, line := range strings.Split(string(specText), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			line = ""
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			line = ""
+		default:
+			fn(i+1, line, previous)
+		}
+		previous = line
+	}
+}
+
+// findTableCellCounts counts the cells in each row of each table, ignoring fenced code blocks.
+// The markdown parser pads or truncates rows to match the header, so this is used to detect uneven rows.
+func findTableCellCounts(specText []byte) [][]int {
+	tables := [][]int{}
+	lines := strings.Split(string(specText), "\n")
+	delimiter := regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+	fence := ""
+
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		case strings.HasPrefix(lines[i], "    ") || strings.HasPrefix(lines[i], "\t"):
+			// indented code
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && delimiter.MatchString(strings.TrimSpace(lines[i+1])):
+			counts := []int{countCells(trimmed)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				counts = append(counts, countCells(strings.TrimSpace(lines[i])))
+			}
+			tables = append(tables, counts)
+			i--
+		}
+	}
+
+	return tables
+}
+
+// countCells counts the cells in a table row, allowing for optional leading and trailing pipes.
+func countCells(row string) int {
+	pipes := 0
+	for i := 0; i < len(row); i++ {
+		if row[i] == '|' && (i == 0 || row[i-1] != '\\') {
+			pipes++
+		}
+	}
+
+	if strings.HasPrefix(row, "|") {
+		pipes--
+	}
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, "\\|") && len(row) > 1 {
+		pipes--
+	}
+
+	return pipes + 1
+}
+
 func (p *specParser) createSpec() {
 	p.closeSpec()
 
@@ -74,6 +184,11 @@ func (p *specParser) createScenario() {
 	})
 	p.currentScenario = p.currentSpec.scenarios[len(p.currentSpec.scenarios)-1]
 	p.textTarget = &p.currentScenario.name
+
+	if len(p.scenarioLines) > 0 {
+		p.currentScenario.line = p.scenarioLines[0]
+		p.scenarioLines = p.scenarioLines[1:]
+	}
 }
 
 func (p *specParser) createStep() {
@@ -85,6 +200,11 @@ func (p *specParser) createStep() {
 		result:  pending,
 	}
 
+	if len(p.stepLines) > 0 {
+		step.line = p.stepLines[0]
+		p.stepLines = p.stepLines[1:]
+	}
+
 	if p.currentScenario != nil {
 		step.scenario = p.currentScenario
 		p.currentScenario.steps = append(p.currentScenario.steps, step)
@@ -99,40 +219,84 @@ func (p *specParser) createStep() {
 	p.textTarget = &p.currentStep.text
 }
 
-func (p *specParser) resolveStepParams(s *step) []string {
+// expandParams replaces each parameterised step with a step for each row of the table
+// providing its parameters.
+func (p *specParser) expandParams(steps []*step) []*step {
+	expanded := []*step{}
+	for _, s := range steps {
+		if resolved := p.resolveStepParams(s); resolved != nil {
+			expanded = append(expanded, resolved...)
+		} else {
+			expanded = append(expanded, s)
+		}
+	}
+	return expanded
+}
+
+// resolveStepParams creates a step for each row of the table providing the step's parameters,
+// substituting them into its text, tables and text blocks. Parameters used only in tables and
+// text blocks must correspond to a column of the table, to avoid confusion with other markup.
+// It returns nil if the step has no parameters, or no table provides them.
+func (p *specParser) resolveStepParams(s *step) []*step {
+	attachmentParams := s.attachmentParams()
+	if len(s.params) == 0 && len(attachmentParams) == 0 {
+		return nil
+	}
+
 	table := stringTable{}
-	resolved := []string{}
 	found := false
 
 	if p.currentScenario != nil {
-		table, found = p.findTableWithStepParams(s, p.currentScenario.tables)
+		table, found = p.findTableWithStepParams(s, attachmentParams, p.currentScenario.tables)
 	}
 
 	if !found {
-		table, found = p.findTableWithStepParams(s, p.currentSpec.tables)
+		table, found = p.findTableWithStepParams(s, attachmentParams, p.currentSpec.tables)
 	}
 
 	if !found {
-		return resolved
+		return nil
 	}
 
-	m := table.columnNameToIndexMap()
+	resolved := []*step{}
 	for _, row := range table[1:] {
-		text := s.text
-		for _, p := range s.params {
-			pname := strings.TrimSuffix(strings.TrimPrefix(p, "<"), ">")
-			pvalue := row[m[pname]]
-			text = strings.Replace(text, p, pvalue, -1)
+		substitute := func(text string) string {
+			for c, name := range table[0] {
+				text = strings.Replace(text, "<"+name+">", row[c], -1)
+			}
+			return text
 		}
-		resolved = append(resolved, text)
+
+		ns := *s
+		ns.text = substitute(s.text)
+		ns.params = nil
+
+		ns.tables = nil
+		for _, t := range s.tables {
+			nt := make(stringTable, len(t))
+			for r := range t {
+				nt[r] = make([]string, len(t[r]))
+				for c := range t[r] {
+					nt[r][c] = substitute(t[r][c])
+				}
+			}
+			ns.tables = append(ns.tables, nt)
+		}
+
+		ns.textBlocks = nil
+		for _, tb := range s.textBlocks {
+			ns.textBlocks = append(ns.textBlocks, TextBlock{Language: tb.Language, Content: substitute(tb.Content)})
+		}
+
+		resolved = append(resolved, &ns)
 	}
 
 	return resolved
 }
 
-func (p *specParser) findTableWithStepParams(s *step, tables []stringTable) (stringTable, bool) {
+func (p *specParser) findTableWithStepParams(s *step, attachmentParams []string, tables []stringTable) (stringTable, bool) {
 	for _, t := range tables {
-		if t.hasParams(s.params) {
+		if t.hasParams(s.params) && (len(s.params) > 0 || t.hasAnyParam(attachmentParams)) {
 			return t, true
 		}
 	}
@@ -146,6 +310,9 @@ func (p *specParser) closeSpec() {
 
 	p.closeScenario()
 
+	p.beforeSteps = p.expandParams(p.beforeSteps)
+	p.afterSteps = p.expandParams(p.afterSteps)
+
 	for _, scenario := range p.currentSpec.scenarios {
 		before := make([]*step, 0, len(p.beforeSteps))
 		for _, b := range p.beforeSteps {
@@ -173,6 +340,7 @@ func (p *specParser) closeScenario() {
 	}
 
 	p.closeStep()
+	p.currentScenario.steps = p.expandParams(p.currentScenario.steps)
 	p.currentScenario = nil
 }
 
@@ -181,14 +349,6 @@ func (p *specParser) closeStep() {
 		return
 	}
 
-	if len(p.currentStep.params) > 0 {
-		s := p.removeLastStep()
-		for _, text := range p.resolveStepParams(s) {
-			p.createStep()
-			p.currentStep.text = text
-		}
-	}
-
 	p.currentStep = nil
 }
 
@@ -217,8 +377,20 @@ func (p *specParser) BlockQuote(out *bytes.Buffer, text []byte) {
 
 }
 
-// BlockHtml not used
+// BlockHtml reads settings for the current spec or scenario from HTML comments
 func (p *specParser) BlockHtml(out *bytes.Buffer, text []byte) {
+	if p.currentSpec == nil {
+		return
+	}
+
+	target := &p.currentSpec.settings
+	if p.currentScenario != nil {
+		target = &p.currentScenario.settings
+	}
+
+	if err := target.parse(string(text)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s.\n", p.currentPath, err)
+	}
 }
 
 // Header creates test hierarchy
@@ -256,7 +428,10 @@ func (p *specParser) List(out *bytes.Buffer, text func() bool, flags int) {
 		out.Truncate(marker)
 	}
 
-	p.removeLastStep()
+	// The step created after the last item isn't a step, so its line belongs to the next list
+	if last := p.removeLastStep(); last.line != 0 {
+		p.stepLines = append([]int{last.line}, p.stepLines...)
+	}
 	p.textTarget = nil
 }
 
@@ -298,15 +473,58 @@ func (p *specParser) Paragraph(out *bytes.Buffer, text func() bool) {
 
 // Table adds the constructed table to the active context
 func (p *specParser) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	rows, even := p.sourceTableRows()
+
 	if p.currentStep != nil {
-		p.currentStep.tables = append(p.currentStep.tables, p.tableRows)
-	} else if p.currentScenario != nil {
-		p.currentScenario.tables = append(p.currentScenario.tables, p.tableRows)
+		p.currentStep.tables = append(p.currentStep.tables, rows)
+		p.currentStep.alignments = append(p.currentStep.alignments, p.tableAlignments)
 	} else {
-		p.currentSpec.tables = append(p.currentSpec.tables, p.tableRows)
+		if !even {
+			fmt.Fprintf(os.Stderr, "warning: table in %s has rows with a different number of cells to its header.\n", p.currentPath)
+		}
+
+		if p.currentScenario != nil {
+			p.currentScenario.tables = append(p.currentScenario.tables, p.tableRows)
+		} else {
+			p.currentSpec.tables = append(p.currentSpec.tables, p.tableRows)
+		}
 	}
 
 	p.tableRows = nil
+	p.tableAlignments = nil
+}
+
+// sourceTableRows restores the number of cells in each row as written in the spec,
+// reporting whether they all match the header.
+func (p *specParser) sourceTableRows() (rows stringTable, even bool) {
+	if len(p.tableCellCounts) == 0 {
+		return p.tableRows, true
+	}
+
+	counts := p.tableCellCounts[0]
+	p.tableCellCounts = p.tableCellCounts[1:]
+
+	if len(counts) != len(p.tableRows) {
+		return p.tableRows, true
+	}
+
+	even = true
+	for r, row := range p.tableRows {
+		n := counts[r]
+		if n == len(row) {
+			rows = append(rows, row)
+			continue
+		}
+
+		even = false
+		if n < len(row) {
+			rows = append(rows, row[:n])
+		} else {
+			rows = append(rows, append(append([]string{}, row...), make([]string, n-len(row))...))
+		}
+	}
+
+	return rows, even
 }
 
 // TableRow saves the current row
@@ -319,12 +537,26 @@ func (p *specParser) TableRow(out *bytes.Buffer, text []byte) {
 
 // TableHeaderCell defines a column in a table
 func (p *specParser) TableHeaderCell(out *bytes.Buffer, text []byte, align int) {
-	p.tableRow = append(p.tableRow, p.lastText)
+	p.tableRow = append(p.tableRow, string(text))
+	p.tableAlignments = append(p.tableAlignments, tableAlignment(align))
 }
 
 // TableCell adds a cell to the current row
 func (p *specParser) TableCell(out *bytes.Buffer, text []byte, align int) {
-	p.tableRow = append(p.tableRow, p.lastText)
+	p.tableRow = append(p.tableRow, string(text))
+}
+
+func tableAlignment(align int) Alignment {
+	switch align {
+	case bf.TABLE_ALIGNMENT_LEFT:
+		return AlignLeft
+	case bf.TABLE_ALIGNMENT_RIGHT:
+		return AlignRight
+	case bf.TABLE_ALIGNMENT_CENTER:
+		return AlignCenter
+	default:
+		return AlignDefault
+	}
 }
 
 // Footnotes not used
@@ -348,6 +580,7 @@ func (p *specParser) AutoLink(out *bytes.Buffer, link []byte, kind int) {
 // CodeSpan output plaintext
 func (p *specParser) CodeSpan(out *bytes.Buffer, text []byte) {
 	s := "`" + string(text) + "`"
+	out.WriteString(s)
 	p.WriteText(s)
 }
 
@@ -403,6 +636,7 @@ func (p *specParser) Entity(out *bytes.Buffer, entity []byte) {
 
 // NormalText output as plaintext
 func (p *specParser) NormalText(out *bytes.Buffer, text []byte) {
+	out.Write(text)
 	p.WriteText(string(text[:]))
 }
 
@@ -2,27 +2,39 @@ package elicit
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
+var paramPattern = regexp.MustCompile(`<[^<>\n]+>`)
+
 type step struct {
 	context    *Context
 	spec       *spec
 	scenario   *scenario
 	text       string
+	line       int
 	params     []string
 	tables     []stringTable
+	alignments [][]Alignment
 	textBlocks []TextBlock
 	impl       func(*testing.T)
+	definition *stepImpl
+	err        error
+	children   []*step
+	deadline   context.Context
+	expired    int32
 	result     result
 	log        bytes.Buffer
 }
 
-func (s *step) setImpl(impl func(*testing.T)) {
+func (s *step) setImpl(definition *stepImpl, impl func(*testing.T)) {
+	s.definition = definition
 	s.impl = impl
 	// We set this to skipped in case it never gets a chance to run
 	s.result = skipped
@@ -31,9 +43,14 @@ func (s *step) setImpl(impl func(*testing.T)) {
 func (s *step) run(scenarioT *testing.T) {
 	defer s.restoreStdout(s.redirectStdout())
 
+	s.context.runningStep = s
+	defer func() { s.context.runningStep = nil }()
+
 	if s.impl == nil {
 		s.result = pending
 		scenarioT.SkipNow()
+	} else if timeout, reason := s.timeout(); timeout > 0 {
+		s.runWithTimeout(scenarioT, timeout, reason)
 	} else {
 		s.impl(scenarioT)
 	}
@@ -41,12 +58,22 @@ func (s *step) run(scenarioT *testing.T) {
 
 func (s *step) createCall(fn reflect.Value, params []reflect.Value) func(*testing.T) {
 	return func(t *testing.T) {
+		// Steps run by other steps share their caller's test, which may already have failed
+		failedBefore := t.Failed()
+
 		defer func() {
-			if rcvr := recover(); rcvr != nil {
+			rcvr := recover()
+
+			// The test may have completed since the step timed out, so it can't be reported
+			if s.hasExpired() {
+				return
+			}
+
+			if rcvr != nil {
 				s.result = panicked
 				fmt.Fprintf(os.Stderr, "panic during step %s/%s/%s/%s: %s\n", s.spec.path, s.spec.name, s.scenario.name, s.text, rcvr)
 				t.Fail()
-			} else if t.Failed() {
+			} else if t.Failed() && !failedBefore {
 				s.result = failed
 			} else if t.Skipped() {
 				s.result = skipped
@@ -60,6 +87,40 @@ func (s *step) createCall(fn reflect.Value, params []reflect.Value) func(*testin
 	}
 }
 
+// attachmentParams finds the possible <param> references in the step's tables and text blocks.
+func (s *step) attachmentParams() []string {
+	params := []string{}
+	for _, t := range s.tables {
+		for _, row := range t {
+			for _, cell := range row {
+				params = append(params, paramPattern.FindAllString(cell, -1)...)
+			}
+		}
+	}
+	for _, tb := range s.textBlocks {
+		params = append(params, paramPattern.FindAllString(tb.Content, -1)...)
+	}
+	return params
+}
+
+// createFailedCall reports a step whose parameters could not be converted.
+// The step fails without calling the implementation.
+func (s *step) createFailedCall(err error) func(*testing.T) {
+	return func(t *testing.T) {
+		s.result = failed
+		fmt.Printf("%s: %s\n", s.location(), err)
+		t.Fail()
+	}
+}
+
+// location identifies where the step is written in its spec file
+func (s *step) location() string {
+	if s.line == 0 {
+		return s.spec.path
+	}
+	return fmt.Sprintf("%s:%d", s.spec.path, s.line)
+}
+
 func (s *step) redirectStdout() (*os.File, chan bool) {
 	stdout := os.Stdout
 
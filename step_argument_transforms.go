@@ -1,8 +1,8 @@
 package elicit
 
 import (
+	"encoding"
 	"fmt"
-	"os"
 	"reflect"
 	"regexp"
 	"regexp/syntax"
@@ -11,33 +11,42 @@ import (
 )
 
 type transform struct {
-	regex *regexp.Regexp
-	fn    interface{}
+	regex   *regexp.Regexp
+	fn      interface{}
+	builtin bool
 }
 
 type transformMap map[reflect.Type][]*transform
 
 const (
-	txWarnPrefix    = "warning: registered transform %q => [%v] "
-	txWarnNotFunc   = txWarnPrefix + "must be a function.\n"
-	txWarnBadRegex  = txWarnPrefix + "has an invalid regular expression: %s.\n"
-	txWarnParamType = txWarnPrefix + "must take one argument of type []string.\n"
-	txWarnReturn    = txWarnPrefix + "must return precisely one value.\n"
+	txErrPrefix       = "registered transform %q => [%v] "
+	txErrNotFunc      = txErrPrefix + "must be a function"
+	txErrBadRegex     = txErrPrefix + "has an invalid regular expression: %s"
+	txErrParamType    = txErrPrefix + "must take one argument of type []string or string"
+	txErrReturn       = txErrPrefix + "must return one value, optionally followed by an error"
+	txErrStringReturn = txErrPrefix + "must return a value and an error"
 )
 
+var (
+	typeString          = reflect.TypeOf((*string)(nil)).Elem()
+	typeStringSlice     = reflect.TypeOf((*[]string)(nil)).Elem()
+	typeError           = reflect.TypeOf((*error)(nil)).Elem()
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	typeValueSetter     = reflect.TypeOf((*valueSetter)(nil)).Elem()
+)
+
+// valueSetter is the method used to set values by implementations of flag.Value
+type valueSetter interface {
+	Set(string) error
+}
+
 func (tm transformMap) init() {
 	tm.register(`.*`, func(params []string) string {
 		return params[0]
 	})
 
-	tm.register(`-?\d+`, func(params []string) int {
-		i, err := strconv.Atoi(params[0])
-
-		if err != nil {
-			panic(fmt.Errorf("converting %q to int: %s", params[0], err))
-		}
-
-		return i
+	tm.register(`-?\d+`, func(params []string) (int, error) {
+		return strconv.Atoi(params[0])
 	})
 
 	tm.register(`(?:.+,\s*)*.+`, func(params []string) []string {
@@ -51,128 +60,377 @@ func (tm transformMap) init() {
 		return ss
 	})
 
-	tm.register(`(?:-?\d+,\s*)*-?\d+`, func(params []string) []int {
+	tm.register(`(?:-?\d+,\s*)*-?\d+`, func(params []string) ([]int, error) {
 		si := []int{}
 
 		for _, s := range strings.Split(params[0], ",") {
 			s = strings.TrimSpace(s)
 			i, err := strconv.Atoi(s)
 			if err != nil {
-				panic(fmt.Errorf("converting %q to int: %s", s, err))
+				return nil, err
 			}
 			si = append(si, i)
 		}
 
-		return si
+		return si, nil
 	})
+
+	tm.registerBuiltins()
+}
+
+func (tm transformMap) register(pattern string, fn interface{}) error {
+	return tm.add(pattern, fn, false)
 }
 
-func (tm transformMap) register(pattern string, fn interface{}) {
-	if regex, typ, ok := tm.validate(pattern, fn); ok {
-		tm[typ] = append(tm[typ], &transform{regex: regex, fn: fn})
+// add registers a transform after the others for its type.
+// Built-in transforms are kept last, so that transforms supplied during setup are tried before them.
+func (tm transformMap) add(pattern string, fn interface{}, builtin bool) error {
+	regex, typ, err := tm.validate(pattern, fn)
+	if err != nil {
+		return err
+	}
+
+	txs := tm[typ]
+	i := len(txs)
+	for !builtin && i > 0 && txs[i-1].builtin {
+		i--
 	}
+
+	tx := &transform{regex: regex, fn: fn, builtin: builtin}
+	tm[typ] = append(txs[:i], append([]*transform{tx}, txs[i:]...)...)
+	return nil
 }
 
-func (tm transformMap) validate(pattern string, transform interface{}) (*regexp.Regexp, reflect.Type, bool) {
+func (tm transformMap) validate(pattern string, transform interface{}) (*regexp.Regexp, reflect.Type, error) {
 	fn := reflect.ValueOf(transform)
 	fnSig := fn.Type()
 
 	if fnSig.Kind() != reflect.Func {
-		fmt.Fprintf(os.Stderr, txWarnNotFunc, pattern, fnSig)
-		return nil, nil, false
+		return nil, nil, fmt.Errorf(txErrNotFunc, pattern, fnSig)
 	}
 
 	cleanPattern := ensureCompleteMatch(pattern)
 	regex, err := regexp.Compile(cleanPattern)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, txWarnBadRegex, pattern, fnSig, err.(*syntax.Error).Code)
-		return nil, nil, false
+		return nil, nil, fmt.Errorf(txErrBadRegex, pattern, fnSig, err.(*syntax.Error).Code)
 	}
 
-	stringSliceType := reflect.TypeOf((*[]string)(nil)).Elem()
-	if fnSig.NumIn() != 1 || fnSig.In(0) != stringSliceType {
-		fmt.Fprintf(os.Stderr, txWarnParamType, pattern, fnSig)
-		return nil, nil, false
+	if fnSig.NumIn() != 1 || (fnSig.In(0) != typeStringSlice && fnSig.In(0) != typeString) {
+		return nil, nil, fmt.Errorf(txErrParamType, pattern, fnSig)
 	}
 
-	if fnSig.NumOut() != 1 {
-		fmt.Fprintf(os.Stderr, txWarnReturn, pattern, fnSig)
-		return nil, nil, false
+	returnsError := fnSig.NumOut() == 2 && fnSig.Out(1) == typeError
+
+	if fnSig.In(0) == typeString {
+		if !returnsError {
+			return nil, nil, fmt.Errorf(txErrStringReturn, pattern, fnSig)
+		}
+	} else if fnSig.NumOut() != 1 && !returnsError {
+		return nil, nil, fmt.Errorf(txErrReturn, pattern, fnSig)
 	}
 
 	typ := fnSig.Out(0)
 
-	return regex, typ, true
+	return regex, typ, nil
 }
 
-func (tm transformMap) convertParams(s *step, fn reflect.Value, stringParams []string) ([]reflect.Value, bool) {
+// conversionError describes a transform which failed to convert a captured parameter
+type conversionError struct {
+	transform string
+	text      string
+	target    reflect.Type
+	err       error
+}
+
+func (e *conversionError) Error() string {
+	return fmt.Sprintf("transform %s could not convert %q to %v: %s", e.transform, e.text, e.target, e.err)
+}
+
+func (tm transformMap) convertParams(s *step, impl *stepImpl, stringParams []string, matched []bool) ([]reflect.Value, bool, error) {
 	if stringParams == nil {
-		return nil, false
+		return nil, false, nil
 	}
 
-	if !tm.paramCountMatch(s, fn, stringParams) {
-		return nil, false
+	fn := reflect.ValueOf(impl.fn)
+
+	if !tm.paramCountMatch(s, impl, stringParams) {
+		return nil, false, nil
 	}
 
 	var c []reflect.Value
-	ok := true
-	if c, ok = tm.convertStringParams(fn, stringParams); !ok {
-		return nil, false
+	var ok bool
+	var err error
+	if tm.bindsFields(impl) {
+		c, ok, err = tm.convertStructParam(fn, impl.fields, stringParams, matched)
+	} else {
+		c, ok, err = tm.convertStringParams(fn, stringParams, matched)
+	}
+
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+
+	for i, rows := range s.tables {
+		tbl, err := makeTable(rows, s.alignments[i], tm)
+		if err != nil {
+			return nil, true, fmt.Errorf("table %d: %s", i+1, err)
+		}
+
+		t, err := tm.convertTable(tbl, fn.Type().In(len(c)))
+		if err != nil {
+			return nil, true, fmt.Errorf("table %d: %s", i+1, err)
+		}
+		c = append(c, t)
 	}
 
-	for _, tbl := range s.tables {
-		c = append(c, reflect.ValueOf(makeTable(tbl)))
+	for i, tb := range s.textBlocks {
+		t, err := s.context.convertTextBlock(tb, fn.Type().In(len(c)))
+		if err != nil {
+			return nil, true, fmt.Errorf("text block %d: %s", i+1, err)
+		}
+		c = append(c, t)
 	}
 
-	for _, tb := range s.textBlocks {
-		c = append(c, reflect.ValueOf(tb))
+	return c, true, nil
+
+}
+
+// convertTable passes the table as is, or decodes it into the type the step takes.
+func (tm transformMap) convertTable(tbl Table, target reflect.Type) (reflect.Value, error) {
+	if target == typeTable {
+		return reflect.ValueOf(tbl), nil
 	}
 
-	return c, true
+	v := reflect.New(target)
+	if err := tbl.Decode(v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
+
+func (tm transformMap) paramCountMatch(s *step, impl *stepImpl, stringParams []string) bool {
+	fn := reflect.ValueOf(impl.fn)
+	paramCount, attachmentCount := s.context.stepImpls.countStepImplParams(fn, impl.regex.NumSubexp())
+	if !attachmentsMatch(fn.Type(), paramCount, s) {
+		return false
+	}
 
+	switch {
+	case attachmentCount != len(s.tables)+len(s.textBlocks):
+		return false
+	case tm.bindsFields(impl):
+		return impl.missing == "" && len(stringParams) == len(impl.fields)+1
+	case fn.Type().IsVariadic():
+		return len(stringParams) >= paramCount-1
+	default:
+		return len(stringParams) == paramCount
+	}
 }
 
-func (tm transformMap) paramCountMatch(s *step, fn reflect.Value, stringParams []string) bool {
-	paramCount, tableParamCount, textBlockParamCount := s.context.stepImpls.countStepImplParams(fn)
-	return len(stringParams) == paramCount && tableParamCount == len(s.tables) && textBlockParamCount == len(s.textBlocks)
+// attachmentsMatch reports whether the step's tables, followed by its text blocks,
+// can populate the parameters following those populated by captures.
+func attachmentsMatch(fnSig reflect.Type, paramCount int, s *step) bool {
+	for i := paramCount; i < fnSig.NumIn(); i++ {
+		pt := fnSig.In(i)
+		if i-paramCount < len(s.tables) && (pt == typeTextBlock || (pt != typeTable && !decodesTable(pt))) {
+			return false
+		}
+	}
+	return true
 }
 
-func (tm transformMap) convertStringParams(fn reflect.Value, stringParams []string) ([]reflect.Value, bool) {
-	c := make([]reflect.Value, len(stringParams))
+func (tm transformMap) convertStringParams(fn reflect.Value, stringParams []string, matched []bool) ([]reflect.Value, bool, error) {
+	fnSig := fn.Type()
+	c := make([]reflect.Value, 0, len(stringParams))
+
 	for i, param := range stringParams {
 		if i == 0 {
-			if fn.Type().In(0) != typeTestingT {
-				return nil, false
+			if fnSig.In(0) != typeTestingT {
+				return nil, false, nil
 			}
-		} else {
-			pt := fn.Type().In(i)
+			c = append(c, reflect.Value{})
+			continue
+		}
 
-			if t, ok := tm.convertParam(param, pt); ok {
-				c[i] = t
-			} else {
-				return nil, false
-			}
+		variadic := fnSig.IsVariadic() && i >= fnSig.NumIn()-1
+		pt := variadicParamType(fnSig, i)
+
+		// Unmatched subgroups are omitted from variadic parameters
+		if variadic && !matched[i] {
+			continue
+		}
+
+		t, ok, err := tm.convertCapture(param, matched[i], pt)
+		if !ok || err != nil {
+			return nil, ok, err
 		}
+		c = append(c, t)
 	}
-	return c, true
+	return c, true, nil
 }
 
-func (tm transformMap) convertParam(param string, target reflect.Type) (reflect.Value, bool) {
+// variadicParamType is the type of the i'th argument to a function,
+// which is the element type of the final parameter if it is variadic.
+func variadicParamType(fnSig reflect.Type, i int) reflect.Type {
+	if last := fnSig.NumIn() - 1; fnSig.IsVariadic() && i >= last {
+		return fnSig.In(last).Elem()
+	}
+	return fnSig.In(i)
+}
+
+// convertStructParam populates the fields of the single struct parameter
+// from the subgroups of the pattern they correspond to.
+func (tm transformMap) convertStructParam(fn reflect.Value, fields []int, stringParams []string, matched []bool) ([]reflect.Value, bool, error) {
+	st := fn.Type().In(1)
+	sv := reflect.New(st).Elem()
+
+	for i, param := range stringParams[1:] {
+		field := sv.Field(fields[i])
+
+		t, ok, err := tm.convertCapture(param, matched[i+1], field.Type())
+		if !ok || err != nil {
+			return nil, ok, err
+		}
+		field.Set(t)
+	}
+
+	return []reflect.Value{{}, sv}, true, nil
+}
+
+// convertCapture converts the text captured by a subgroup. Subgroups which
+// didn't participate in the match become the zero value of the target type,
+// and pointer types may be populated using transforms for the type they point to.
+func (tm transformMap) convertCapture(param string, matched bool, target reflect.Type) (reflect.Value, bool, error) {
+	if !matched {
+		return reflect.Zero(target), true, nil
+	}
+
+	if len(tm[target]) > 0 {
+		return tm.convertParam(param, target)
+	}
+
+	// Transforms for the type pointed to take precedence over its unmarshaler
+	if target.Kind() == reflect.Ptr && len(tm[target.Elem()]) > 0 {
+		v, ok, err := tm.convertParam(param, target.Elem())
+		if !ok || err != nil {
+			return v, ok, err
+		}
+
+		ptr := reflect.New(target.Elem())
+		ptr.Elem().Set(v)
+		return ptr, true, nil
+	}
+
+	if unmarshalsText(target) {
+		v, err := unmarshalText(param, target)
+		return v, true, err
+	}
+
+	return reflect.Value{}, false, nil
+}
+
+// convertCell converts the text of a table cell to the target type.
+func (tm transformMap) convertCell(cell string, target reflect.Type) (reflect.Value, error) {
+	v, ok, err := tm.convertCapture(cell, true, target)
+	if err == nil && !ok {
+		err = fmt.Errorf("no transform for %v matches %q", target, cell)
+	}
+	return v, err
+}
+
+// canConvert reports whether there are any transforms for the type
+func (tm transformMap) canConvert(target reflect.Type) bool {
+	if len(tm[target]) > 0 || unmarshalsText(target) {
+		return true
+	}
+	return target.Kind() == reflect.Ptr && (len(tm[target.Elem()]) > 0 || unmarshalsText(target.Elem()))
+}
+
+// convertParam converts the text using the first transform for the target type whose pattern it matches.
+// If the transform fails, the parameter is still considered a match, but the error is returned.
+func (tm transformMap) convertParam(param string, target reflect.Type) (reflect.Value, bool, error) {
 	for _, tx := range tm[target] {
 		params := tx.regex.FindStringSubmatch(param)
 		if params == nil {
 			continue
 		}
 
-		fn := reflect.ValueOf(tx.fn)
+		v, err := tx.call(params)
+		return v, true, err
+	}
+
+	return reflect.Value{}, false, nil
+}
+
+func (tx *transform) String() string {
+	p := tx.regex.String()
+	p = strings.TrimPrefix(p, "^")
+	p = strings.TrimSuffix(p, "$")
+	return fmt.Sprintf("%q => [%v]", p, reflect.TypeOf(tx.fn))
+}
 
-		in := []reflect.Value{
-			reflect.ValueOf(params),
+func (tx *transform) call(params []string) (v reflect.Value, err error) {
+	fn := reflect.ValueOf(tx.fn)
+	target := fn.Type().Out(0)
+
+	defer func() {
+		if rcvr := recover(); rcvr != nil {
+			err = &conversionError{transform: tx.String(), text: params[0], target: target, err: fmt.Errorf("panic: %v", rcvr)}
 		}
+	}()
+
+	in := []reflect.Value{
+		reflect.ValueOf(params),
+	}
+
+	if fn.Type().In(0) == typeString {
+		in[0] = reflect.ValueOf(params[0])
+	}
+
+	out := fn.Call(in)
 
-		out := fn.Call(in)
-		return reflect.ValueOf(out[0].Interface()), true
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, &conversionError{transform: tx.String(), text: params[0], target: target, err: out[1].Interface().(error)}
 	}
 
-	return reflect.Value{}, false
+	return reflect.ValueOf(out[0].Interface()), nil
+}
+
+// unmarshalsText reports whether values of the type can set themselves from text,
+// either as an encoding.TextUnmarshaler or with a flag.Value style Set method.
+func unmarshalsText(target reflect.Type) bool {
+	ptr := target
+	if target.Kind() != reflect.Ptr {
+		ptr = reflect.PtrTo(target)
+	}
+	return ptr.Implements(typeTextUnmarshaler) || ptr.Implements(typeValueSetter)
+}
+
+func unmarshalText(param string, target reflect.Type) (reflect.Value, error) {
+	var v reflect.Value
+	if target.Kind() == reflect.Ptr {
+		v = reflect.New(target.Elem())
+	} else {
+		v = reflect.New(target)
+	}
+
+	var err error
+	var method string
+	switch u := v.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		method = "UnmarshalText"
+		err = u.UnmarshalText([]byte(param))
+	case valueSetter:
+		method = "Set"
+		err = u.Set(param)
+	}
+
+	if err != nil {
+		return reflect.Value{}, &conversionError{transform: fmt.Sprintf("(%v).%s", v.Type(), method), text: param, target: target, err: err}
+	}
+
+	if target.Kind() == reflect.Ptr {
+		return v, nil
+	}
+	return v.Elem(), nil
 }
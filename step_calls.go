@@ -0,0 +1,55 @@
+package elicit
+
+import (
+	"testing"
+)
+
+// RunStep runs the step matching the text from within a step implementation.
+// Any Tables or TextBlocks the step takes should be supplied as attachments.
+// The step is reported beneath the step which ran it, and if it doesn't pass
+// then neither does the calling step.
+func (ctx *Context) RunStep(t *testing.T, text string, attachments ...interface{}) {
+	parent := ctx.runningStep
+	if parent == nil {
+		t.Fatalf("running step %q: steps may only be run from a step implementation", text)
+	}
+
+	s := &step{
+		context:  ctx,
+		spec:     parent.spec,
+		scenario: parent.scenario,
+		text:     text,
+		line:     parent.line,
+		deadline: parent.deadline,
+		result:   pending,
+	}
+
+	for _, a := range attachments {
+		switch a := a.(type) {
+		case Table:
+			s.tables = append(s.tables, a.toStringTable())
+			s.alignments = append(s.alignments, a.Alignments)
+		case TextBlock:
+			s.textBlocks = append(s.textBlocks, a)
+		default:
+			t.Fatalf("running step %q: attachments must be a Table or TextBlock, not %T", text, a)
+		}
+	}
+
+	parent.children = append(parent.children, s)
+
+	ctx.matchStepImpl(s)
+
+	if s.impl == nil {
+		t.Fatalf("running step %q: no unique implementation found", text)
+	}
+
+	ctx.runningStep = s
+	defer func() { ctx.runningStep = parent }()
+
+	s.impl(t)
+
+	if s.result != passed {
+		t.FailNow()
+	}
+}
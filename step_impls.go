@@ -2,7 +2,6 @@ package elicit
 
 import (
 	"fmt"
-	"os"
 	"reflect"
 	"regexp"
 	"regexp/syntax"
@@ -11,40 +10,58 @@ import (
 )
 
 type stepImpl struct {
-	regex *regexp.Regexp
-	fn    interface{}
+	regex    *regexp.Regexp
+	fn       interface{}
+	priority int
+	fields   []int
+	missing  string
+	library  *StepLibrary
+	declared interface{}
 }
 
 type stepImpls []*stepImpl
 
 const (
-	stepWarnPrefix      = "warning: registered step %q => [%v] "
-	stepWarnNotFunc     = stepWarnPrefix + "must be a function.\n"
-	stepWarnBadRegex    = stepWarnPrefix + "has an invalid regular expression: %s.\n"
-	stepWarnFirstParam  = stepWarnPrefix + "has an invalid implementation. The first parameter must be of type *testing.T.\n"
-	stepWarnParamCount  = stepWarnPrefix + "captures %d parameter%s but the supplied implementation takes %d.\n"
+	stepErrPrefix       = "registered step %q => [%v] "
+	stepErrNotFunc      = stepErrPrefix + "must be a function"
+	stepErrBadRegex     = stepErrPrefix + "has an invalid regular expression: %s"
+	stepErrFirstParam   = stepErrPrefix + "has an invalid implementation. The first parameter must be of type *testing.T"
+	stepErrParamCount   = stepErrPrefix + "captures %d parameter%s but the supplied implementation takes %d"
+	stepWarnNoField     = "warning: registered step %s captures %q but %v has no corresponding field.\n"
 	stepWarnNoTransform = "warning: registered step %s has a parameter type %q for which no transforms exist.\n"
 	stepWarnNotUsed     = "warning: registered step %s is not used.\n"
 	stepWarnAmbiguous   = "warning: step %q is ambiguous:\n"
 )
 
 var (
-	typeTestingT = reflect.TypeOf((*testing.T)(nil))
+	typeTestingT  = reflect.TypeOf((*testing.T)(nil))
+	typeTable     = reflect.TypeOf((*Table)(nil)).Elem()
+	typeTextBlock = reflect.TypeOf((*TextBlock)(nil)).Elem()
 )
 
 func (s *stepImpl) String() string {
 	p := s.regex.String()
 	p = strings.TrimLeft(p, "^")
 	p = strings.TrimRight(p, "$")
-	return fmt.Sprintf("%q => [%v]", p, reflect.TypeOf(s.fn))
+	fn := s.fn
+	if s.declared != nil {
+		fn = s.declared
+	}
+	if s.library != nil {
+		return fmt.Sprintf("%q => [%v] from %s", p, reflect.TypeOf(fn), s.library)
+	}
+	return fmt.Sprintf("%q => [%v]", p, reflect.TypeOf(fn))
 }
 
-func (si *stepImpls) register(pattern string, stepFunc interface{}) *stepImpl {
-	if r, ok := si.validate(pattern, stepFunc); ok {
-		*si = append(*si, &stepImpl{regex: r, fn: stepFunc})
-		return (*si)[len(*si)-1]
+func (si *stepImpls) register(pattern string, stepFunc interface{}, priority int) (*stepImpl, error) {
+	impl, err := si.validate(pattern, stepFunc)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	impl.priority = priority
+	*si = append(*si, impl)
+	return impl, nil
 }
 
 func ensureCompleteMatch(pattern string) string {
@@ -59,55 +76,142 @@ func ensureCompleteMatch(pattern string) string {
 	return pattern
 }
 
-func (si *stepImpls) validate(pattern string, impl interface{}) (*regexp.Regexp, bool) {
+func (si *stepImpls) validate(pattern string, impl interface{}) (*stepImpl, error) {
 	fn := reflect.ValueOf(impl)
 	fnSig := fn.Type()
 
 	if fnSig.Kind() != reflect.Func {
-		fmt.Fprintf(os.Stderr, stepWarnNotFunc, pattern, fnSig)
-		return nil, false
+		return nil, fmt.Errorf(stepErrNotFunc, pattern, fnSig)
 	}
 
 	cleanPattern := strings.TrimSpace(pattern)
 	cleanPattern = ensureCompleteMatch(pattern)
 	regex, err := regexp.Compile(cleanPattern)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, stepWarnBadRegex, pattern, fnSig, err.(*syntax.Error).Code)
-		return nil, false
+		return nil, fmt.Errorf(stepErrBadRegex, pattern, fnSig, err.(*syntax.Error).Code)
 	}
 
 	patternCaptures := regex.NumSubexp()
 	if fnSig.NumIn() == 0 || fnSig.In(0) != typeTestingT {
-		fmt.Fprintf(os.Stderr, stepWarnFirstParam, pattern, fnSig)
-		return nil, false
+		return nil, fmt.Errorf(stepErrFirstParam, pattern, fnSig)
 	}
 
 	// Note paramCount includes the first *testing.T parameter
-	if paramCount, _, _ := si.countStepImplParams(fn); paramCount-1 != patternCaptures {
+	paramCount, _ := si.countStepImplParams(fn, patternCaptures)
+
+	// Whether the captures populate the struct's fields is decided once all transforms are registered
+	if paramCount == 2 && hasNamedCaptures(regex) && fnSig.In(1).Kind() == reflect.Struct {
+		fields, missing := captureFields(regex, fnSig.In(1))
+		return &stepImpl{regex: regex, fn: impl, fields: fields, missing: missing}, nil
+	}
+
+	// Variadic parameters accept any number of remaining subgroups
+	if fnSig.IsVariadic() && paramCount-2 <= patternCaptures {
+		return &stepImpl{regex: regex, fn: impl}, nil
+	}
+
+	if paramCount-1 != patternCaptures {
 		plural := ""
 		if patternCaptures != 1 {
 			plural = "s"
 		}
-		fmt.Fprintf(os.Stderr, stepWarnParamCount, pattern, fnSig, patternCaptures, plural, paramCount-1)
-		return nil, false
+		return nil, fmt.Errorf(stepErrParamCount, pattern, fnSig, patternCaptures, plural, paramCount-1)
+	}
+
+	return &stepImpl{regex: regex, fn: impl}, nil
+}
+
+// match finds the text captured by each subgroup of the pattern,
+// along with whether the subgroup participated in the match.
+func (s *stepImpl) match(text string) (params []string, matched []bool) {
+	indices := s.regex.FindStringSubmatchIndex(text)
+	if indices == nil {
+		return nil, nil
+	}
+
+	params = make([]string, len(indices)/2)
+	matched = make([]bool, len(indices)/2)
+
+	for i := range params {
+		start, end := indices[2*i], indices[2*i+1]
+		if matched[i] = start >= 0; matched[i] {
+			params[i] = text[start:end]
+		}
+	}
+
+	return params, matched
+}
+
+// bindsFields reports whether the step's captures populate the fields of its struct parameter.
+// Structs which can be converted from text by a transform or unmarshaler take a single capture instead.
+func (tm transformMap) bindsFields(impl *stepImpl) bool {
+	if impl.fields == nil && impl.missing == "" {
+		return false
+	}
+	return !tm.canConvert(reflect.TypeOf(impl.fn).In(1))
+}
+
+// hasNamedCaptures reports whether every subgroup in the regex is named.
+func hasNamedCaptures(regex *regexp.Regexp) bool {
+	names := regex.SubexpNames()[1:]
+	for _, n := range names {
+		if n == "" {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// captureFields finds the index of the struct field corresponding to each named subgroup.
+// Fields match by their `elicit:"name"` tag, or otherwise case-insensitively by name.
+// If a subgroup has no corresponding field, its name is returned as missing.
+func captureFields(regex *regexp.Regexp, typ reflect.Type) (fields []int, missing string) {
+	names := regex.SubexpNames()[1:]
+	fields = make([]int, len(names))
+
+	for i, n := range names {
+		if fields[i] = fieldIndex(typ, n); fields[i] < 0 {
+			return nil, n
+		}
 	}
 
-	return regex, true
+	return fields, ""
 }
 
-func (si *stepImpls) countStepImplParams(fn reflect.Value) (params, tables, textBlocks int) {
-	tableType := reflect.TypeOf((*Table)(nil)).Elem()
-	textBlockType := reflect.TypeOf((*TextBlock)(nil)).Elem()
+func fieldIndex(typ reflect.Type, name string) int {
+	for f := 0; f < typ.NumField(); f++ {
+		field := typ.Field(f)
+		if field.PkgPath != "" {
+			continue
+		}
 
-	params = fn.Type().NumIn()
+		if tag, ok := field.Tag.Lookup("elicit"); ok {
+			if tag == name {
+				return f
+			}
+		} else if strings.EqualFold(field.Name, name) {
+			return f
+		}
+	}
+	return -1
+}
+
+// countStepImplParams separates the parameters populated by captures from the trailing
+// parameters populated by a step's tables and text blocks (its attachments).
+// Table and TextBlock parameters are always attachments, as are parameters which a table
+// or text block can be decoded into, when the pattern doesn't capture enough subgroups to populate them.
+func (si *stepImpls) countStepImplParams(fn reflect.Value, captures int) (params, attachments int) {
+	fnSig := fn.Type()
+
+	params = fnSig.NumIn()
 	for p := params - 1; p >= 0; p-- {
-		thisParam := fn.Type().In(p)
-		if thisParam == tableType {
+		thisParam := fnSig.In(p)
+		if thisParam == typeTable || thisParam == typeTextBlock {
 			params--
-			tables++
-		} else if thisParam == textBlockType {
+			attachments++
+		} else if params-1 > captures && decodesAttachment(thisParam) && !(fnSig.IsVariadic() && p == fnSig.NumIn()-1) {
 			params--
-			textBlocks++
+			attachments++
 		} else {
 			break
 		}
@@ -115,3 +219,92 @@ func (si *stepImpls) countStepImplParams(fn reflect.Value) (params, tables, text
 
 	return
 }
+
+// decodesAttachment reports whether a table or text block can be decoded into values of the type,
+// or the type it points to. Other types are never attachments, so must be captured.
+func decodesAttachment(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return decodesTable(typ)
+}
+
+// decodesTable reports whether a table can be decoded into values of the type,
+// i.e. slices of structs for tables with headers, or maps and structs for vertical tables.
+func decodesTable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Key() == typeString
+	case reflect.Struct:
+		return true
+	default:
+		return isStructSlice(typ)
+	}
+}
+
+// isStructSlice reports whether the type is a slice of structs, or of pointers to structs.
+func isStructSlice(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
+	}
+	elem := typ.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// specificity summarises how narrowly a step pattern matches text.
+// It is used to choose between several implementations matching the same step.
+type specificity struct {
+	priority  int
+	captures  int
+	wildcards int
+	classes   int
+	literals  int
+}
+
+func (s *stepImpl) specificity() specificity {
+	sp := specificity{
+		priority: s.priority,
+		captures: s.regex.NumSubexp(),
+	}
+
+	if re, err := syntax.Parse(s.regex.String(), syntax.Perl); err == nil {
+		sp.count(re)
+	}
+
+	return sp
+}
+
+func (sp *specificity) count(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sp.wildcards++
+	case syntax.OpCharClass:
+		sp.classes++
+	case syntax.OpLiteral:
+		sp.literals += len(re.Rune)
+	}
+
+	for _, sub := range re.Sub {
+		sp.count(sub)
+	}
+}
+
+// compare returns a positive number if sp is more specific than o,
+// a negative number if it is less specific and zero if they are equivalent.
+func (sp specificity) compare(o specificity) int {
+	switch {
+	case sp.priority != o.priority:
+		return sp.priority - o.priority
+	case sp.captures != o.captures:
+		return o.captures - sp.captures
+	case sp.wildcards != o.wildcards:
+		return o.wildcards - sp.wildcards
+	case sp.classes != o.classes:
+		return o.classes - sp.classes
+	default:
+		return sp.literals - o.literals
+	}
+}
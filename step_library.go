@@ -0,0 +1,104 @@
+package elicit
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// StepLibrary bundles steps, transforms and hooks so they can be shared
+// between projects. Register one with Context.Use().
+type StepLibrary struct {
+	Name            string
+	Version         string
+	Steps           Steps
+	Transforms      Transforms
+	BeforeSpecs     Hooks
+	AfterSpecs      Hooks
+	BeforeScenarios Hooks
+	AfterScenarios  Hooks
+	BeforeSteps     Hooks
+	AfterSteps      Hooks
+}
+
+const (
+	libWarnConflict = "warning: registered step %s conflicts with %s.\n"
+	libWarnOverlap  = "warning: step %q matches steps from more than one library: %s.\n"
+)
+
+func (l *StepLibrary) String() string {
+	if l.Version == "" {
+		return l.Name
+	}
+	return l.Name + "@" + l.Version
+}
+
+// Use registers the steps, transforms and hooks from the supplied library
+func (ctx *Context) Use(lib *StepLibrary) *Context {
+	for p, fn := range lib.Transforms {
+		if err := ctx.transforms.register(p, fn); err != nil {
+			warn(fmt.Errorf("library %s: %s", lib, err))
+		}
+	}
+
+	patterns := make([]string, 0, len(lib.Steps))
+	for p := range lib.Steps {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	for _, p := range patterns {
+		if err := ctx.registerStep(p, lib.Steps[p], 0, lib); err != nil {
+			warn(fmt.Errorf("library %s: %s", lib, err))
+		}
+	}
+
+	ctx.beforeSpec = append(ctx.beforeSpec, lib.BeforeSpecs...)
+	ctx.afterSpec = append(ctx.afterSpec, lib.AfterSpecs...)
+	ctx.beforeScenario = append(ctx.beforeScenario, lib.BeforeScenarios...)
+	ctx.afterScenario = append(ctx.afterScenario, lib.AfterScenarios...)
+	ctx.beforeStep = append(ctx.beforeStep, lib.BeforeSteps...)
+	ctx.afterStep = append(ctx.afterStep, lib.AfterSteps...)
+
+	return ctx
+}
+
+// checkConflicts warns if the step has the same pattern as one registered by a different library
+func (ctx *Context) checkConflicts(si *stepImpl) {
+	for _, other := range ctx.stepImpls {
+		if other == si || other.library == si.library || other.regex.String() != si.regex.String() {
+			continue
+		}
+
+		if other.library != nil || si.library != nil {
+			fmt.Fprintf(os.Stderr, libWarnConflict, si, other)
+		}
+	}
+}
+
+// checkOverlaps warns if the step matches steps from different libraries, whose patterns may differ
+func checkOverlaps(s *step, candidates []stepImplCandidate) {
+	sources := map[string]bool{}
+	fromLibrary := false
+	for _, c := range candidates {
+		if c.impl.library != nil {
+			sources[c.impl.library.String()] = true
+			fromLibrary = true
+		} else {
+			sources["the context"] = true
+		}
+	}
+
+	if !fromLibrary || len(sources) < 2 {
+		return
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, libWarnOverlap, s.text, strings.Join(names, ", "))
+}
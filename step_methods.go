@@ -0,0 +1,70 @@
+package elicit
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// StepDefiner is implemented by types whose methods are step implementations.
+// Steps maps patterns to the names of the methods implementing them.
+type StepDefiner interface {
+	Steps() map[string]string
+}
+
+const (
+	methodErrNoSteps  = "%v does not implement elicit.StepDefiner"
+	methodErrNoMethod = "registered step %q => [%v.%s] is not an exported method"
+)
+
+// WithStepsFrom registers the methods of obj as step implementations.
+// The obj must implement StepDefiner, which maps each pattern to a method name.
+// Alternatively, obj may be a factory function of the form func() T, where T
+// implements StepDefiner. In this case a new receiver is created before each
+// scenario so that state is not shared between them.
+func (ctx *Context) WithStepsFrom(obj interface{}) *Context {
+	v := reflect.ValueOf(obj)
+
+	receiver := func() reflect.Value { return v }
+
+	if t := v.Type(); t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 1 {
+		current := v.Call(nil)[0]
+		receiver = func() reflect.Value { return current }
+
+		ctx.BeforeScenarios(func() {
+			current = v.Call(nil)[0]
+		})
+	}
+
+	definer, ok := receiver().Interface().(StepDefiner)
+	if !ok {
+		warn(fmt.Errorf(methodErrNoSteps, receiver().Type()))
+		return ctx
+	}
+
+	methods := definer.Steps()
+	patterns := make([]string, 0, len(methods))
+	for p := range methods {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	for _, p := range patterns {
+		name := methods[p]
+		method := receiver().MethodByName(name)
+
+		if !method.IsValid() {
+			warn(fmt.Errorf(methodErrNoMethod, p, receiver().Type(), name))
+			continue
+		}
+
+		// Bind the method to whichever receiver is current when the step is called
+		fn := reflect.MakeFunc(method.Type(), func(args []reflect.Value) []reflect.Value {
+			return receiver().MethodByName(name).Call(args)
+		})
+
+		ctx.Step(p, fn.Interface())
+	}
+
+	return ctx
+}
@@ -0,0 +1,154 @@
+package elicit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var typeContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// bindStepContext adapts step implementations taking a context.Context as their second parameter,
+// so the context of the running step is supplied when they are called.
+func (ctx *Context) bindStepContext(fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	fnSig := v.Type()
+	if fnSig.Kind() != reflect.Func || fnSig.NumIn() < 2 || fnSig.In(1) != typeContext {
+		return fn
+	}
+
+	in := []reflect.Type{fnSig.In(0)}
+	for i := 2; i < fnSig.NumIn(); i++ {
+		in = append(in, fnSig.In(i))
+	}
+	out := []reflect.Type{}
+	for i := 0; i < fnSig.NumOut(); i++ {
+		out = append(out, fnSig.Out(i))
+	}
+
+	bound := reflect.FuncOf(in, out, fnSig.IsVariadic())
+	return reflect.MakeFunc(bound, func(args []reflect.Value) []reflect.Value {
+		params := append([]reflect.Value{args[0], reflect.ValueOf(ctx.stepContext())}, args[1:]...)
+		if fnSig.IsVariadic() {
+			return v.CallSlice(params)
+		}
+		return v.Call(params)
+	}).Interface()
+}
+
+// stepContext is cancelled when the running step times out
+func (ctx *Context) stepContext() context.Context {
+	if s := ctx.runningStep; s != nil && s.deadline != nil {
+		return s.deadline
+	}
+	return context.Background()
+}
+
+// timeout is the time the step may take, limited by its own timeout and
+// the time remaining for its scenario. It is zero if there is no limit.
+// The reason describes which limit applies.
+func (s *step) timeout() (timeout time.Duration, reason string) {
+	settings := s.scenario.effectiveSettings()
+	timeout = settings.stepTimeout
+	reason = fmt.Sprintf("timed out after %s", timeout)
+
+	if !s.scenario.deadline.IsZero() {
+		remaining := time.Until(s.scenario.deadline)
+		if remaining <= 0 {
+			remaining = time.Nanosecond
+		}
+		if timeout == 0 || remaining < timeout {
+			timeout = remaining
+			reason = fmt.Sprintf("timed out as the scenario took longer than %s", settings.scenarioTimeout)
+		}
+	}
+
+	return timeout, reason
+}
+
+// timeoutGrace is how long a step which has timed out is given to return,
+// e.g. after noticing its context has been cancelled.
+const timeoutGrace = 100 * time.Millisecond
+
+// runWithTimeout runs the step implementation in its own goroutine, abandoning it
+// if it doesn't complete in time. Its context is cancelled either way.
+func (s *step) runWithTimeout(scenarioT *testing.T, timeout time.Duration, reason string) {
+	deadline, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	s.deadline = deadline
+
+	done := make(chan struct{})
+	goroutine := make(chan string, 1)
+
+	go func() {
+		defer close(done)
+		goroutine <- currentGoroutine()
+		s.impl(scenarioT)
+	}()
+
+	id := <-goroutine
+
+	select {
+	case <-done:
+		return
+	case <-deadline.Done():
+	}
+
+	atomic.StoreInt32(&s.expired, 1)
+	s.result = timedOut
+	scenarioT.Fail()
+
+	stack := ""
+	select {
+	case <-done:
+	case <-time.After(timeoutGrace):
+		stack = goroutineStack(id)
+	}
+
+	if stack != "" {
+		reason += "\n" + stack
+	}
+	fmt.Println(reason)
+	fmt.Fprintf(os.Stderr, "timeout during step %s/%s/%s/%s: %s\n", s.spec.path, s.spec.name, s.scenario.name, s.text, reason)
+}
+
+// hasExpired reports whether the step has been abandoned after timing out
+func (s *step) hasExpired() bool {
+	return atomic.LoadInt32(&s.expired) == 1
+}
+
+// currentGoroutine identifies the calling goroutine by the header of its stack trace
+func currentGoroutine() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	if i := bytes.Index(buf, []byte(" [")); i >= 0 {
+		return string(buf[:i])
+	}
+	return ""
+}
+
+// goroutineStack returns the stack trace of the identified goroutine
+func goroutineStack(id string) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, []byte(id+" [")) {
+			return string(stack)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,289 @@
+// Package cli provides elicit steps for specifying command-line tools.
+//
+// Each scenario runs in a new temporary directory, in which files can be
+// created from text blocks and commands can be run and their results checked.
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpwalkerdine/elicit"
+)
+
+// Session holds the state of the steps for the current scenario
+type Session struct {
+	dir      string
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// New creates a session whose steps can be registered with Context.Use()
+func New() *Session {
+	return &Session{}
+}
+
+// Dir is the temporary directory for the current scenario
+func (s *Session) Dir() string {
+	return s.dir
+}
+
+// Stdout is the standard output of the last command
+func (s *Session) Stdout() string {
+	return s.stdout
+}
+
+// Stderr is the standard error of the last command
+func (s *Session) Stderr() string {
+	return s.stderr
+}
+
+// ExitCode is the exit code of the last command
+func (s *Session) ExitCode() int {
+	return s.exitCode
+}
+
+// Library bundles the steps and hooks of the session
+func (s *Session) Library() *elicit.StepLibrary {
+	return &elicit.StepLibrary{
+		Name: "cli",
+		Steps: elicit.Steps{
+			"Create an? `(.+)` file:":             s.createFile,
+			"Replace the `(.+)` file:":            s.replaceFile,
+			"Run `(.+)`":                          s.run,
+			"Run `(.+)` with input:":              s.runWithInput,
+			"Run `(.+)` with environment:":        s.runWithEnvironment,
+			"The exit code is (-?\\d+)":           s.checkExitCode,
+			"(stdout|stderr) contains:":           s.checkOutputContains,
+			"(stdout|stderr) contains the lines:": s.checkOutputLines,
+			"(stdout|stderr) is empty":            s.checkOutputEmpty,
+			"The file `(.+)` contains:":           s.checkFileContains,
+			"The file `(.+)` is:":                 s.checkFileEquals,
+			"The file `(.+)` exists":              s.checkFileExists,
+			"The file `(.+)` does not exist":      s.checkFileNotExists,
+		},
+		BeforeScenarios: elicit.Hooks{s.createDir},
+		AfterScenarios:  elicit.Hooks{s.removeDir},
+	}
+}
+
+func (s *Session) createDir() {
+	dir, err := ioutil.TempDir("", "elicit_cli")
+	if err != nil {
+		panic(fmt.Errorf("creating temporary directory: %s", err))
+	}
+
+	*s = Session{dir: dir}
+}
+
+func (s *Session) removeDir() {
+	if err := os.RemoveAll(s.dir); err != nil {
+		panic(fmt.Errorf("removing temporary directory %q: %s", s.dir, err))
+	}
+}
+
+func (s *Session) path(filename string) string {
+	return filepath.Join(s.dir, filename)
+}
+
+func (s *Session) createFile(t *testing.T, filename string, text elicit.TextBlock) {
+	if _, err := os.Stat(s.path(filename)); err == nil {
+		t.Fatalf("creating file: %s already exists", filename)
+	}
+	s.writeFile(t, filename, text.Content)
+}
+
+func (s *Session) replaceFile(t *testing.T, filename string, text elicit.TextBlock) {
+	s.writeFile(t, filename, text.Content)
+}
+
+func (s *Session) writeFile(t *testing.T, filename, contents string) {
+	path := s.path(filename)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("creating directory for %s: %s", filename, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("writing %s: %s", filename, err)
+	}
+}
+
+func (s *Session) run(t *testing.T, command string) {
+	s.execute(t, command, "", nil)
+}
+
+func (s *Session) runWithInput(t *testing.T, command string, input elicit.TextBlock) {
+	s.execute(t, command, input.Content, nil)
+}
+
+func (s *Session) runWithEnvironment(t *testing.T, command string, env elicit.Table) {
+	vars := []string{}
+	for _, row := range env.Rows {
+		vars = append(vars, row[env.Columns[0]]+"="+row[env.Columns[1]])
+	}
+	s.execute(t, command, "", vars)
+}
+
+func (s *Session) execute(t *testing.T, command, input string, env []string) {
+	args, err := splitCommand(command)
+	if err != nil {
+		t.Fatalf("parsing command %q: %s", command, err)
+	}
+
+	if len(args) == 0 {
+		t.Fatal("no command supplied")
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = s.dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	s.stdout, s.stderr, s.exitCode = stdout.String(), stderr.String(), 0
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		s.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("running %q: %s", command, err)
+	}
+}
+
+// splitCommand splits a command into arguments separated by whitespace,
+// treating text inside single or double quotes as a single argument.
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+func (s *Session) output(stream string) string {
+	if stream == "stderr" {
+		return s.stderr
+	}
+	return s.stdout
+}
+
+func (s *Session) checkExitCode(t *testing.T, expected int) {
+	if s.exitCode != expected {
+		t.Errorf("expected exit code %d, got %d\n\nstderr:\n%s", expected, s.exitCode, quote(s.stderr))
+	}
+}
+
+func (s *Session) checkOutputContains(t *testing.T, stream string, text elicit.TextBlock) {
+	actual := s.output(stream)
+	expected := strings.TrimSpace(text.Content)
+
+	if !strings.Contains(actual, expected) {
+		t.Errorf("\n\nExpected %s:\n\n%s\n\n to contain:\n\n%s\n", stream, quote(actual), quote(expected))
+	}
+}
+
+func (s *Session) checkOutputLines(t *testing.T, stream string, text elicit.TextBlock) {
+	actual := s.output(stream)
+
+	missing := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(text.Content), "\n") {
+		if !strings.Contains(actual, line) {
+			missing = append(missing, line)
+		}
+	}
+
+	if len(missing) > 0 {
+		t.Errorf("\n\nExpected %s:\n\n%s\n\n to contain the lines:\n\n%s\n", stream, quote(actual), quote(strings.Join(missing, "\n")))
+	}
+}
+
+func (s *Session) checkOutputEmpty(t *testing.T, stream string) {
+	if actual := s.output(stream); actual != "" {
+		t.Errorf("\n\nExpected %s to be empty, got:\n\n%s\n", stream, quote(actual))
+	}
+}
+
+func (s *Session) readFile(t *testing.T, filename string) string {
+	contents, err := ioutil.ReadFile(s.path(filename))
+	if err != nil {
+		t.Fatalf("reading %s: %s", filename, err)
+	}
+	return string(contents)
+}
+
+func (s *Session) checkFileContains(t *testing.T, filename string, text elicit.TextBlock) {
+	actual := s.readFile(t, filename)
+	expected := strings.TrimSpace(text.Content)
+
+	if !strings.Contains(actual, expected) {
+		t.Errorf("\n\nExpected %s:\n\n%s\n\n to contain:\n\n%s\n", filename, quote(actual), quote(expected))
+	}
+}
+
+func (s *Session) checkFileEquals(t *testing.T, filename string, text elicit.TextBlock) {
+	actual := strings.TrimSpace(s.readFile(t, filename))
+	expected := strings.TrimSpace(text.Content)
+
+	if actual != expected {
+		t.Errorf("\n\nExpected %s:\n\n%s\n\n to equal:\n\n%s\n", filename, quote(actual), quote(expected))
+	}
+}
+
+func (s *Session) checkFileExists(t *testing.T, filename string) {
+	if _, err := os.Stat(s.path(filename)); err != nil {
+		t.Errorf("expected %s to exist: %s", filename, err)
+	}
+}
+
+func (s *Session) checkFileNotExists(t *testing.T, filename string) {
+	if _, err := os.Stat(s.path(filename)); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to exist", filename)
+	}
+}
+
+func quote(s string) string {
+	s = strings.TrimSpace(s)
+	return "  | " + strings.Join(strings.Split(s, "\n"), "\n  | ")
+}
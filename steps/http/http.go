@@ -0,0 +1,329 @@
+// Package http provides elicit steps for specifying HTTP APIs.
+//
+// Requests are sent either directly to an http.Handler, in which case no
+// network is involved, or to a server at a base URL.
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mpwalkerdine/elicit"
+)
+
+// Session holds the state of the steps for the current scenario
+type Session struct {
+	handler  nethttp.Handler
+	baseURL  string
+	client   *nethttp.Client
+	response *nethttp.Response
+	body     []byte
+}
+
+// ForHandler creates a session which serves requests using the handler
+func ForHandler(handler nethttp.Handler) *Session {
+	return &Session{handler: handler}
+}
+
+// ForURL creates a session which sends requests to the server at baseURL
+func ForURL(baseURL string) *Session {
+	return &Session{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &nethttp.Client{},
+	}
+}
+
+// Response is the last response received
+func (s *Session) Response() *nethttp.Response {
+	return s.response
+}
+
+// Body is the body of the last response received
+func (s *Session) Body() []byte {
+	return s.body
+}
+
+// Library bundles the steps and hooks of the session
+func (s *Session) Library() *elicit.StepLibrary {
+	const send = "Send an? (GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS) request to `(.+)`"
+
+	return &elicit.StepLibrary{
+		Name: "http",
+		Steps: elicit.Steps{
+			send:                                   s.send,
+			send + " with headers:":                s.sendWithHeaders,
+			send + " with body:":                   s.sendWithBody,
+			send + " with headers and body:":       s.sendWithHeadersAndBody,
+			"The response status is (\\d+)":        s.checkStatus,
+			"The response header `(.+)` is `(.*)`": s.checkHeader,
+			"The response headers include:":        s.checkHeaders,
+			"The response body contains:":          s.checkBodyContains,
+			"The response body is JSON:":           s.checkJSONEquals,
+			"The response body contains JSON:":     s.checkJSONSubset,
+			"The JSON at `(.+)` is `(.+)`":         s.checkJSONPath,
+		},
+		BeforeScenarios: elicit.Hooks{s.reset},
+	}
+}
+
+func (s *Session) reset() {
+	s.response = nil
+	s.body = nil
+}
+
+func (s *Session) send(t *testing.T, method, path string) {
+	s.do(t, method, path, nil, nil)
+}
+
+func (s *Session) sendWithHeaders(t *testing.T, method, path string, headers elicit.Table) {
+	s.do(t, method, path, &headers, nil)
+}
+
+func (s *Session) sendWithBody(t *testing.T, method, path string, body elicit.TextBlock) {
+	s.do(t, method, path, nil, &body)
+}
+
+func (s *Session) sendWithHeadersAndBody(t *testing.T, method, path string, headers elicit.Table, body elicit.TextBlock) {
+	s.do(t, method, path, &headers, &body)
+}
+
+func (s *Session) do(t *testing.T, method, path string, headers *elicit.Table, body *elicit.TextBlock) {
+	var content io.Reader
+	if body != nil {
+		content = strings.NewReader(body.Content)
+	}
+
+	url := s.baseURL + path
+	if s.handler != nil {
+		url = "http://elicit.test" + path
+	}
+
+	req, err := nethttp.NewRequest(method, url, content)
+	if err != nil {
+		t.Fatalf("creating %s request to %s: %s", method, path, err)
+	}
+
+	if body != nil && body.Language == "json" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if headers != nil {
+		for _, row := range headers.Rows {
+			req.Header.Set(row[headers.Columns[0]], row[headers.Columns[1]])
+		}
+	}
+
+	if s.handler != nil {
+		rec := httptest.NewRecorder()
+		s.handler.ServeHTTP(rec, req)
+		s.response = rec.Result()
+	} else if s.response, err = s.client.Do(req); err != nil {
+		t.Fatalf("sending %s request to %s: %s", method, url, err)
+	}
+
+	defer s.response.Body.Close()
+
+	if s.body, err = ioutil.ReadAll(s.response.Body); err != nil {
+		t.Fatalf("reading response body: %s", err)
+	}
+}
+
+func (s *Session) requireResponse(t *testing.T) {
+	if s.response == nil {
+		t.Fatal("no request has been sent")
+	}
+}
+
+func (s *Session) checkStatus(t *testing.T, expected int) {
+	s.requireResponse(t)
+
+	if s.response.StatusCode != expected {
+		t.Errorf("expected status %d, got %d\n\n%s", expected, s.response.StatusCode, quote(string(s.body)))
+	}
+}
+
+func (s *Session) checkHeader(t *testing.T, name, expected string) {
+	s.requireResponse(t)
+
+	if actual := s.response.Header.Get(name); actual != expected {
+		t.Errorf("expected header %s to be %q, got %q", name, expected, actual)
+	}
+}
+
+func (s *Session) checkHeaders(t *testing.T, headers elicit.Table) {
+	for _, row := range headers.Rows {
+		s.checkHeader(t, row[headers.Columns[0]], row[headers.Columns[1]])
+	}
+}
+
+func (s *Session) checkBodyContains(t *testing.T, text elicit.TextBlock) {
+	s.requireResponse(t)
+
+	expected := strings.TrimSpace(text.Content)
+	if !bytes.Contains(s.body, []byte(expected)) {
+		t.Errorf("\n\nExpected body:\n\n%s\n\n to contain:\n\n%s\n", quote(string(s.body)), quote(expected))
+	}
+}
+
+func (s *Session) checkJSONEquals(t *testing.T, text elicit.TextBlock) {
+	expected, actual := s.decodeJSON(t, text)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\n\nExpected body:\n\n%s\n\n to equal:\n\n%s\n", quote(string(s.body)), quote(text.Content))
+	}
+}
+
+func (s *Session) checkJSONSubset(t *testing.T, text elicit.TextBlock) {
+	expected, actual := s.decodeJSON(t, text)
+
+	if err := matchSubset("$", expected, actual); err != nil {
+		t.Errorf("\n\nExpected body:\n\n%s\n\n to contain:\n\n%s\n\n%s", quote(string(s.body)), quote(text.Content), err)
+	}
+}
+
+func (s *Session) checkJSONPath(t *testing.T, path, value string) {
+	_, actual := s.decodeJSON(t, elicit.TextBlock{Content: "null"})
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(value), &expected); err != nil {
+		// Treat anything which isn't valid JSON as a string
+		expected = value
+	}
+
+	found, err := evaluatePath(path, actual)
+	if err != nil {
+		t.Fatalf("evaluating %s: %s", path, err)
+	}
+
+	if !reflect.DeepEqual(expected, found) {
+		t.Errorf("expected %s to be %s, got %s", path, encode(expected), encode(found))
+	}
+}
+
+func (s *Session) decodeJSON(t *testing.T, text elicit.TextBlock) (expected, actual interface{}) {
+	s.requireResponse(t)
+
+	if err := json.Unmarshal([]byte(text.Content), &expected); err != nil {
+		t.Fatalf("parsing expected JSON: %s", err)
+	}
+
+	if err := json.Unmarshal(s.body, &actual); err != nil {
+		t.Fatalf("parsing response body as JSON: %s\n\n%s", err, quote(string(s.body)))
+	}
+
+	return
+}
+
+// matchSubset checks that every value in expected is present in actual.
+// Objects may contain additional keys, but arrays must be the same length.
+func matchSubset(path string, expected, actual interface{}) error {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %s", path, encode(actual))
+		}
+		for k, v := range e {
+			av, ok := a[k]
+			if !ok {
+				return fmt.Errorf("%s: missing key %q", path, k)
+			}
+			if err := matchSubset(path+"."+k, v, av); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %s", path, encode(actual))
+		}
+		if len(a) != len(e) {
+			return fmt.Errorf("%s: expected %d elements, got %d", path, len(e), len(a))
+		}
+		for i := range e {
+			if err := matchSubset(fmt.Sprintf("%s[%d]", path, i), e[i], a[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Errorf("%s: expected %s, got %s", path, encode(expected), encode(actual))
+		}
+	}
+	return nil
+}
+
+// evaluatePath finds the value at a simple JSONPath such as $.items[0].name
+func evaluatePath(path string, value interface{}) (interface{}, error) {
+	rest := strings.TrimPrefix(path, "$")
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			rest = rest[end+1:]
+
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot select %q from %s", key, encode(value))
+			}
+			value = obj[key]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in %q", rest)
+			}
+			index := rest[1:end]
+			rest = rest[end+1:]
+
+			if key := strings.Trim(index, `'"`); key != index {
+				obj, ok := value.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("cannot select %q from %s", key, encode(value))
+				}
+				value = obj[key]
+				continue
+			}
+
+			var i int
+			if _, err := fmt.Sscanf(index, "%d", &i); err != nil {
+				return nil, fmt.Errorf("invalid index %q", index)
+			}
+
+			arr, ok := value.([]interface{})
+			if !ok || i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("cannot select [%d] from %s", i, encode(value))
+			}
+			value = arr[i]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+
+	return value, nil
+}
+
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func quote(s string) string {
+	s = strings.TrimSpace(s)
+	return "  | " + strings.Join(strings.Split(s, "\n"), "\n  | ")
+}
@@ -13,14 +13,6 @@ func (t *stringTable) hasColumn(cname string) bool {
 	return false
 }
 
-func (t *stringTable) columnNameToIndexMap() map[string]int {
-	m := make(map[string]int, len((*t)[0]))
-	for i, c := range (*t)[0] {
-		m[c] = i
-	}
-	return m
-}
-
 func (t *stringTable) hasParams(params []string) bool {
 	for _, p := range params {
 		pname := strings.TrimSuffix(strings.TrimPrefix(p, "<"), ">")
@@ -30,3 +22,13 @@ func (t *stringTable) hasParams(params []string) bool {
 	}
 	return true
 }
+
+func (t *stringTable) hasAnyParam(params []string) bool {
+	for _, p := range params {
+		pname := strings.TrimSuffix(strings.TrimPrefix(p, "<"), ">")
+		if t.hasColumn(pname) {
+			return true
+		}
+	}
+	return false
+}
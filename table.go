@@ -1,25 +1,241 @@
 package elicit
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // Table holds test data from the spec
 type Table struct {
-	Columns []string
-	Rows    []map[string]string
+	Columns    []string
+	Rows       []map[string]string
+	Alignments []Alignment
+
+	cells      [][]string
+	transforms transformMap
 }
 
-func makeTable(rows [][]string) Table {
+// Alignment of a table column, as specified by colons in the row separating the header
+type Alignment int
+
+// Column alignments
+const (
+	AlignDefault Alignment = iota
+	AlignLeft
+	AlignRight
+	AlignCenter
+)
+
+func makeTable(rows [][]string, alignments []Alignment, tm transformMap) (Table, error) {
 	t := Table{
-		Columns: rows[0],
+		Columns:    rows[0],
+		Alignments: alignments,
+		cells:      rows,
+		transforms: tm,
 	}
 
 	cc := len(t.Columns)
 
-	for _, row := range rows[1:] {
+	for r, row := range rows[1:] {
+		if len(row) != cc {
+			return Table{}, fmt.Errorf("row %d has %d cells but the header has %d", r+1, len(row), cc)
+		}
+
 		m := make(map[string]string, cc)
-		for c := 0; c < cc; c++ {
+		for c := cc - 1; c >= 0; c-- {
 			m[t.Columns[c]] = row[c]
 		}
 		t.Rows = append(t.Rows, m)
 	}
 
-	return t
+	if t.Alignments == nil {
+		t.Alignments = make([]Alignment, cc)
+	}
+
+	return t, nil
+}
+
+func (t Table) toStringTable() stringTable {
+	if t.cells != nil {
+		return t.cells
+	}
+
+	rows := stringTable{t.Columns}
+
+	for _, r := range t.Rows {
+		row := make([]string, len(t.Columns))
+		for c, name := range t.Columns {
+			row[c] = r[name]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// Raw returns every row of the table, including the header, with the cells in their original order.
+func (t Table) Raw() [][]string {
+	raw := [][]string{}
+	for _, row := range t.toStringTable() {
+		raw = append(raw, append([]string{}, row...))
+	}
+	return raw
+}
+
+// Cell returns the text of a cell by its position, where row 0 is the first row after the header,
+// or "" if there is no such cell.
+// This includes cells in columns with duplicate names, which Rows cannot hold.
+func (t Table) Cell(row, col int) string {
+	rows := t.toStringTable()
+	if row < 0 || row+1 >= len(rows) || col < 0 || col >= len(rows[row+1]) {
+		return ""
+	}
+	return rows[row+1][col]
+}
+
+// Column returns the text of each cell in the first column with the given name,
+// or nil if there is no such column.
+func (t Table) Column(name string) []string {
+	for c, n := range t.Columns {
+		if n != name {
+			continue
+		}
+
+		cells := []string{}
+		for _, row := range t.toStringTable()[1:] {
+			cells = append(cells, row[c])
+		}
+		return cells
+	}
+	return nil
+}
+
+// Transpose swaps the rows and columns of the table, so the first column becomes the header.
+// A table without any columns transposes to an empty table.
+func (t Table) Transpose() Table {
+	raw := t.toStringTable()
+	if len(raw[0]) == 0 {
+		return Table{transforms: t.transforms}
+	}
+
+	transposed := make(stringTable, len(raw[0]))
+	for c := range transposed {
+		transposed[c] = make([]string, len(raw))
+		for r, row := range raw {
+			transposed[c][r] = row[c]
+		}
+	}
+
+	tt, _ := makeTable(transposed, nil, t.transforms)
+	return tt
+}
+
+// Decode stores the contents of the table in the value pointed to by v.
+//
+// If v points to a slice, each row is stored in an element of the slice. The elements
+// must be structs, or pointers to structs, with a field for each column.
+//
+// If v points to a map with string keys, or to a struct, the table is read vertically.
+// It must have two columns, and every row, including the header, is a key and value.
+//
+// Columns and keys correspond to fields by their `elicit:"name"` tag, or otherwise
+// case-insensitively by name. Each value is converted to the type of its field or map
+// elements using the registered transforms.
+func (t Table) Decode(v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || !decodesTable(ptr.Elem().Type()) {
+		return fmt.Errorf("decoding table: %T is not a pointer to a slice of structs, a map with string keys or a struct", v)
+	}
+
+	tm := t.transforms
+	if tm == nil {
+		tm = transformMap{}
+		tm.init()
+	}
+
+	if ptr.Elem().Kind() == reflect.Slice {
+		return t.decodeRows(ptr.Elem(), tm)
+	}
+
+	return t.decodeVertical(ptr.Elem(), tm)
+}
+
+func (t Table) decodeRows(slice reflect.Value, tm transformMap) error {
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+
+	fields := make([]int, len(t.Columns))
+	for c, name := range t.Columns {
+		if fields[c] = fieldIndex(structType, name); fields[c] < 0 {
+			return fmt.Errorf("column %q has no corresponding field in %v", name, structType)
+		}
+	}
+
+	rows := reflect.MakeSlice(slice.Type(), 0, len(t.Rows))
+	for r, row := range t.Rows {
+		sv := reflect.New(structType).Elem()
+
+		for c, name := range t.Columns {
+			field := sv.Field(fields[c])
+			cell, err := tm.convertCell(row[name], field.Type())
+			if err != nil {
+				return fmt.Errorf("row %d, column %q: %s", r+1, name, err)
+			}
+			field.Set(cell)
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			sv = sv.Addr()
+		}
+		rows = reflect.Append(rows, sv)
+	}
+
+	slice.Set(rows)
+	return nil
+}
+
+// decodeVertical reads key/value pairs from each row, including the header, into a map or struct.
+func (t Table) decodeVertical(v reflect.Value, tm transformMap) error {
+	if len(t.Columns) != 2 {
+		return fmt.Errorf("vertical table must have 2 columns, not %d", len(t.Columns))
+	}
+
+	pairs := [][2]string{{t.Columns[0], t.Columns[1]}}
+	for _, row := range t.Rows {
+		pairs = append(pairs, [2]string{row[t.Columns[0]], row[t.Columns[1]]})
+	}
+
+	if v.Kind() == reflect.Map && v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+
+	for r, pair := range pairs {
+		key, value := pair[0], pair[1]
+
+		if v.Kind() == reflect.Map {
+			cell, err := tm.convertCell(value, v.Type().Elem())
+			if err != nil {
+				return fmt.Errorf("row %d, key %q: %s", r+1, key, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), cell)
+			continue
+		}
+
+		f := fieldIndex(v.Type(), key)
+		if f < 0 {
+			return fmt.Errorf("row %d, key %q has no corresponding field in %v", r+1, key, v.Type())
+		}
+
+		field := v.Field(f)
+		cell, err := tm.convertCell(value, field.Type())
+		if err != nil {
+			return fmt.Errorf("row %d, key %q: %s", r+1, key, err)
+		}
+		field.Set(cell)
+	}
+
+	return nil
 }
@@ -0,0 +1,438 @@
+package elicit
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// DiffOption controls how tables are compared
+type DiffOption int
+
+const (
+	// Ordered requires the actual rows to be in the same order as the expected rows (the default)
+	Ordered DiffOption = 0
+	// Unordered allows the actual rows to be in any order
+	Unordered DiffOption = 1 << iota
+	// Subset allows the actual rows to include rows which aren't expected
+	Subset
+)
+
+// TableDiff describes the differences between an expected table and actual rows
+type TableDiff struct {
+	columns []string
+	rows    []diffRow
+}
+
+type diffKind int
+
+const (
+	rowEqual diffKind = iota
+	rowMissing
+	rowSurplus
+	rowChanged
+)
+
+var diffMarkers = map[diffKind]string{
+	rowEqual:   " ",
+	rowMissing: "-",
+	rowSurplus: "+",
+	rowChanged: "~",
+}
+
+type diffRow struct {
+	kind  diffKind
+	cells []string
+}
+
+// actualCell holds an actual value and whether it exists at all
+type actualCell struct {
+	value reflect.Value
+	found bool
+}
+
+type actualRow map[string]actualCell
+
+// Diff compares the table to the actual rows, which may be a Table, or a slice of
+// structs, pointers to structs or maps with string keys. Columns correspond to fields
+// as they do for Decode, and columns the table doesn't have are ignored.
+//
+// Cells are equal if the expected text converts to the actual value using the registered
+// transforms, or if the actual value is formatted as the expected text.
+func (t Table) Diff(actual interface{}, options ...DiffOption) (TableDiff, error) {
+	var opts DiffOption
+	for _, o := range options {
+		opts |= o
+	}
+
+	rows, err := t.actualRows(actual)
+	if err != nil {
+		return TableDiff{}, err
+	}
+
+	tm := t.transforms
+	if tm == nil {
+		tm = transformMap{}
+		tm.init()
+	}
+
+	c := tableComparison{table: t, actual: rows, tm: tm}
+
+	var diff []diffRow
+	if opts&Unordered != 0 {
+		diff = c.unordered()
+	} else {
+		diff = c.ordered()
+	}
+
+	if opts&Subset != 0 {
+		filtered := diff[:0]
+		for _, r := range diff {
+			if r.kind != rowSurplus {
+				filtered = append(filtered, r)
+			}
+		}
+		diff = filtered
+	}
+
+	return TableDiff{columns: t.Columns, rows: diff}, nil
+}
+
+// AssertEqual fails the test if the actual rows differ from the table,
+// writing the differences to the step's output.
+func (t Table) AssertEqual(test *testing.T, actual interface{}, options ...DiffOption) {
+	diff, err := t.Diff(actual, options...)
+	if err != nil {
+		fmt.Println(err)
+		test.Fail()
+		return
+	}
+
+	if !diff.Equal() {
+		fmt.Print(diff)
+		test.Fail()
+	}
+}
+
+// Equal reports whether there were no differences
+func (d TableDiff) Equal() bool {
+	for _, r := range d.rows {
+		if r.kind != rowEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the table with each row marked as missing (-), surplus (+) or changed (~).
+// Changed cells show the expected and actual values.
+func (d TableDiff) String() string {
+	if d.Equal() {
+		return "tables are equal\n"
+	}
+
+	widths := make([]int, len(d.columns))
+	for c, name := range d.columns {
+		widths[c] = utf8.RuneCountInString(name)
+	}
+	for _, r := range d.rows {
+		for c, cell := range r.cells {
+			if w := utf8.RuneCountInString(cell); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("tables differ (- missing, + surplus, ~ changed):\n")
+
+	writeRow := func(marker string, cells []string) {
+		buf.WriteString(marker)
+		for c, cell := range cells {
+			buf.WriteString(" | ")
+			buf.WriteString(cell)
+			if c < len(cells)-1 {
+				buf.WriteString(strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell)))
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	writeRow(" ", d.columns)
+	for _, r := range d.rows {
+		writeRow(diffMarkers[r.kind], r.cells)
+	}
+
+	return buf.String()
+}
+
+// actualRows extracts the cells for each column from the actual rows
+func (t Table) actualRows(actual interface{}) ([]actualRow, error) {
+	if tbl, ok := actual.(Table); ok {
+		rows := []actualRow{}
+		for _, r := range tbl.Rows {
+			row := actualRow{}
+			for _, name := range t.Columns {
+				v, found := r[name]
+				row[name] = actualCell{reflect.ValueOf(v), found}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(actual))
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("comparing table: %T is not a Table or a slice", actual)
+	}
+
+	rows := []actualRow{}
+	for i := 0; i < v.Len(); i++ {
+		elem := reflect.Indirect(v.Index(i))
+		row := actualRow{}
+
+		for _, name := range t.Columns {
+			switch {
+			case elem.Kind() == reflect.Map && elem.Type().Key().Kind() == reflect.String:
+				cell := elem.MapIndex(reflect.ValueOf(name).Convert(elem.Type().Key()))
+				row[name] = actualCell{cell, cell.IsValid()}
+			case elem.Kind() == reflect.Struct:
+				f := fieldIndex(elem.Type(), name)
+				if f < 0 {
+					return nil, fmt.Errorf("comparing table: column %q has no corresponding field in %v", name, elem.Type())
+				}
+				row[name] = actualCell{elem.Field(f), true}
+			default:
+				return nil, fmt.Errorf("comparing table: %v is not a struct or a map with string keys", v.Type().Elem())
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+type tableComparison struct {
+	table  Table
+	actual []actualRow
+	tm     transformMap
+}
+
+// ordered aligns the rows using their longest common subsequence
+func (c tableComparison) ordered() []diffRow {
+	exp, act := c.table.Rows, c.actual
+
+	lcs := make([][]int, len(exp)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(act)+1)
+	}
+	for i := len(exp) - 1; i >= 0; i-- {
+		for j := len(act) - 1; j >= 0; j-- {
+			if c.rowsEqual(exp[i], act[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []diffRow{}
+	var missing, surplus []int
+
+	flush := func() {
+		diff = append(diff, c.pairChanged(missing, surplus, false)...)
+		missing, surplus = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(exp) || j < len(act) {
+		switch {
+		case i < len(exp) && j < len(act) && c.rowsEqual(exp[i], act[j]):
+			flush()
+			diff = append(diff, c.equalRow(exp[i]))
+			i++
+			j++
+		case j == len(act) || (i < len(exp) && lcs[i+1][j] >= lcs[i][j+1]):
+			missing = append(missing, i)
+			i++
+		default:
+			surplus = append(surplus, j)
+			j++
+		}
+	}
+	flush()
+
+	return diff
+}
+
+// unordered matches each expected row with the first equal actual row not already matched
+func (c tableComparison) unordered() []diffRow {
+	used := make([]bool, len(c.actual))
+	matches := make([]int, len(c.table.Rows))
+	var missing, surplus []int
+
+	for i, e := range c.table.Rows {
+		matches[i] = -1
+		for j, a := range c.actual {
+			if !used[j] && c.rowsEqual(e, a) {
+				used[j] = true
+				matches[i] = j
+				break
+			}
+		}
+		if matches[i] < 0 {
+			missing = append(missing, i)
+		}
+	}
+
+	for j := range c.actual {
+		if !used[j] {
+			surplus = append(surplus, j)
+		}
+	}
+
+	diff := []diffRow{}
+	for i, j := range matches {
+		if j >= 0 {
+			diff = append(diff, c.equalRow(c.table.Rows[i]))
+		}
+	}
+
+	return append(diff, c.pairChanged(missing, surplus, true)...)
+}
+
+// pairChanged pairs missing rows with surplus rows which share some of their cells,
+// reporting them as changed. Ordered comparisons pair rows in turn, unordered
+// comparisons pair each missing row with the most similar surplus row.
+func (c tableComparison) pairChanged(missing, surplus []int, unordered bool) []diffRow {
+	diff := []diffRow{}
+	paired := make([]bool, len(surplus))
+
+	for _, i := range missing {
+		best, bestScore := -1, 0
+		for k, j := range surplus {
+			if paired[k] {
+				continue
+			}
+			if score := c.similarity(c.table.Rows[i], c.actual[j]); score > bestScore {
+				best, bestScore = k, score
+			}
+			if !unordered {
+				break
+			}
+		}
+
+		if best < 0 {
+			diff = append(diff, c.missingRow(c.table.Rows[i]))
+			continue
+		}
+
+		paired[best] = true
+		diff = append(diff, c.changedRow(c.table.Rows[i], c.actual[surplus[best]]))
+	}
+
+	for k, j := range surplus {
+		if !paired[k] {
+			diff = append(diff, c.surplusRow(c.actual[j]))
+		}
+	}
+
+	return diff
+}
+
+func (c tableComparison) rowsEqual(e map[string]string, a actualRow) bool {
+	return c.similarity(e, a) == len(c.table.Columns)
+}
+
+func (c tableComparison) similarity(e map[string]string, a actualRow) int {
+	n := 0
+	for _, name := range c.table.Columns {
+		if c.cellEqual(e[name], a[name]) {
+			n++
+		}
+	}
+	return n
+}
+
+func (c tableComparison) cellEqual(expected string, actual actualCell) bool {
+	if !actual.found {
+		return false
+	}
+
+	v := actual.value
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return expected == formatCell(v)
+	}
+
+	if v.Kind() == reflect.String {
+		return expected == v.String()
+	}
+
+	if ev, err := c.tm.convertCell(expected, v.Type()); err == nil && reflect.DeepEqual(ev.Interface(), v.Interface()) {
+		return true
+	}
+
+	return expected == formatCell(v)
+}
+
+func formatCell(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (c tableComparison) equalRow(e map[string]string) diffRow {
+	return c.expectedRow(rowEqual, e)
+}
+
+func (c tableComparison) missingRow(e map[string]string) diffRow {
+	return c.expectedRow(rowMissing, e)
+}
+
+func (c tableComparison) expectedRow(kind diffKind, e map[string]string) diffRow {
+	cells := make([]string, len(c.table.Columns))
+	for i, name := range c.table.Columns {
+		cells[i] = e[name]
+	}
+	return diffRow{kind: kind, cells: cells}
+}
+
+func (c tableComparison) surplusRow(a actualRow) diffRow {
+	cells := make([]string, len(c.table.Columns))
+	for i, name := range c.table.Columns {
+		cells[i] = c.actualText(a[name])
+	}
+	return diffRow{kind: rowSurplus, cells: cells}
+}
+
+func (c tableComparison) changedRow(e map[string]string, a actualRow) diffRow {
+	cells := make([]string, len(c.table.Columns))
+	for i, name := range c.table.Columns {
+		cells[i] = e[name]
+		if !c.cellEqual(e[name], a[name]) {
+			cells[i] += " → " + c.actualText(a[name])
+		}
+	}
+	return diffRow{kind: rowChanged, cells: cells}
+}
+
+func (c tableComparison) actualText(a actualCell) string {
+	if !a.found {
+		return "<missing>"
+	}
+	v := a.value
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return formatCell(v)
+}
@@ -1,7 +1,85 @@
 package elicit
 
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
 // TextBlock represents a code block from markdown
 type TextBlock struct {
 	Language string
 	Content  string
 }
+
+// TextDecoder stores the content of a text block in the value pointed to by v
+type TextDecoder func(content string, v interface{}) error
+
+// WithTextDecoder registers a decoder for text blocks in the given language.
+// Steps may then take a parameter of any type the decoder supports in place of a TextBlock.
+// Decoders for json, xml and csv are registered by default.
+func (ctx *Context) WithTextDecoder(language string, decode TextDecoder) *Context {
+	ctx.textDecoders[language] = decode
+	return ctx
+}
+
+func (ctx *Context) registerTextDecoders() {
+	ctx.textDecoders = map[string]TextDecoder{
+		"json": func(content string, v interface{}) error {
+			return json.Unmarshal([]byte(content), v)
+		},
+		"xml": func(content string, v interface{}) error {
+			return xml.Unmarshal([]byte(content), v)
+		},
+		"csv": ctx.decodeCSV,
+	}
+}
+
+// decodeCSV reads the text into a Table, using the first record as its header.
+// The Table may then be decoded into any of the types a step table can.
+func (ctx *Context) decodeCSV(content string, v interface{}) error {
+	r := csv.NewReader(strings.NewReader(content))
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no records")
+	}
+
+	tbl, err := makeTable(records, nil, ctx.transforms)
+	if err != nil {
+		return err
+	}
+
+	if t, ok := v.(*Table); ok {
+		*t = tbl
+		return nil
+	}
+
+	return tbl.Decode(v)
+}
+
+// convertTextBlock passes the text block as is, or decodes it into the type the step takes.
+func (ctx *Context) convertTextBlock(tb TextBlock, target reflect.Type) (reflect.Value, error) {
+	if target == typeTextBlock {
+		return reflect.ValueOf(tb), nil
+	}
+
+	decode, ok := ctx.textDecoders[tb.Language]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no decoder is registered for %q text blocks to decode into %v", tb.Language, target)
+	}
+
+	v := reflect.New(target)
+	if err := decode(tb.Content, v.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("decoding %s into %v: %s", tb.Language, target, err)
+	}
+
+	return v.Elem(), nil
+}
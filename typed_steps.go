@@ -0,0 +1,47 @@
+package elicit
+
+import (
+	"testing"
+)
+
+// Step0 registers a step implementation which takes no parameters.
+// Unlike Context.Step(), an invalid registration is returned as an error.
+func Step0(ctx *Context, pattern string, fn func(*testing.T)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Step1 registers a step implementation which takes one parameter.
+// The pattern must capture one subgroup, unless the parameter is a Table or TextBlock.
+func Step1[A any](ctx *Context, pattern string, fn func(*testing.T, A)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Step2 registers a step implementation which takes two parameters.
+// The pattern must capture a subgroup for each parameter preceding any Tables or TextBlocks.
+func Step2[A, B any](ctx *Context, pattern string, fn func(*testing.T, A, B)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Step3 registers a step implementation which takes three parameters.
+// The pattern must capture a subgroup for each parameter preceding any Tables or TextBlocks.
+func Step3[A, B, C any](ctx *Context, pattern string, fn func(*testing.T, A, B, C)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Step4 registers a step implementation which takes four parameters.
+// The pattern must capture a subgroup for each parameter preceding any Tables or TextBlocks.
+func Step4[A, B, C, D any](ctx *Context, pattern string, fn func(*testing.T, A, B, C, D)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Step5 registers a step implementation which takes five parameters.
+// The pattern must capture a subgroup for each parameter preceding any Tables or TextBlocks.
+func Step5[A, B, C, D, E any](ctx *Context, pattern string, fn func(*testing.T, A, B, C, D, E)) error {
+	return ctx.registerStep(pattern, fn, 0, nil)
+}
+
+// Transform registers a step argument transform producing values of type T.
+// If the transform returns an error, the step using it fails.
+func Transform[T any](ctx *Context, pattern string, fn func([]string) (T, error)) error {
+	return ctx.transforms.register(pattern, fn)
+}